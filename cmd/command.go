@@ -22,14 +22,14 @@ func NewCommandCmd() *cobra.Command {
 	commandCmd := &cobra.Command{
 		Use:   "command",
 		Short: "Command an instance",
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
 			civoProvider, err := civo.NewProvider(true, log.Default)
 			if err != nil {
 				return err
 			}
 
 			return cmd.Run(
-				context.Background(),
+				cobraCmd.Context(),
 				civoProvider,
 				provider.FromEnvironment(),
 				log.Default,
@@ -54,9 +54,22 @@ func (cmd *CommandCmd) Run(
 	}
 
 	// get instance
-	instance, err := civo.GetDevpodInstance(providerCivo)
-	sshClient, err := ssh.NewSSHPassClient("civo", instance.PublicIP+":22", instance.InitialPassword)
+	instance, err := civo.GetDevpodInstance(ctx, providerCivo)
+	if err != nil {
+		return err
+	}
+
+	address := civo.InstanceAddress(instance, !providerCivo.Config.PublicIP)
+	if instance.Status != civo.StatusActive || address == "" {
+		return fmt.Errorf("instance %s is not ready yet, status is %s", instance.Hostname, instance.Status)
+	}
+
+	privateKey, err := ssh.GetPrivateKeyRawBase(providerCivo.Config.MachineFolder)
+	if err != nil {
+		return errors.Wrap(err, "load private key")
+	}
 
+	sshClient, err := ssh.NewSSHClient(civo.InitialUser, address+":22", privateKey)
 	if err != nil {
 		return errors.Wrap(err, "create ssh client")
 	}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// ConsoleCmd holds the cmd flags
+type ConsoleCmd struct{}
+
+// NewConsoleCmd defines a command
+func NewConsoleCmd() *cobra.Command {
+	cmd := &ConsoleCmd{}
+	consoleCmd := &cobra.Command{
+		Use:   "console",
+		Short: "Print the URL of the web console of an instance",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			civoProvider, err := civo.NewProvider(true, log.Default)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(
+				cobraCmd.Context(),
+				civoProvider,
+				log.Default,
+			)
+		},
+	}
+
+	return consoleCmd
+}
+
+// Run runs the command logic
+func (cmd *ConsoleCmd) Run(
+	ctx context.Context,
+	providerCivo *civo.CivoProvider,
+	logs log.Logger,
+) error {
+	url, err := civo.ConsoleURL(ctx, providerCivo)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(os.Stdout, url)
+	return err
+}
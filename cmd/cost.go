@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// CostCmd holds the cmd flags
+type CostCmd struct {
+	All    bool
+	Output string
+}
+
+// NewCostCmd defines a command
+func NewCostCmd() *cobra.Command {
+	cmd := &CostCmd{}
+	costCmd := &cobra.Command{
+		Use:   "cost",
+		Short: "Show the cost of the machine or of all DevPod instances",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(
+				cobraCmd.Context(),
+				log.Default,
+			)
+		},
+	}
+
+	costCmd.Flags().BoolVar(&cmd.All, "all", false, "Show all DevPod instances in the region, grouped by owner")
+	costCmd.Flags().StringVarP(&cmd.Output, "output", "o", "table", "The output format, one of table or json")
+	return costCmd
+}
+
+// Run runs the command logic
+func (cmd *CostCmd) Run(
+	ctx context.Context,
+	logs log.Logger,
+) error {
+	if cmd.Output != "table" && cmd.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, use table or json", cmd.Output)
+	}
+
+	var (
+		client    civogo.Clienter
+		instances []civogo.Instance
+	)
+	if cmd.All {
+		var err error
+		client, err = civo.NewClient()
+		if err != nil {
+			return err
+		}
+
+		instances, err = civo.ListDevpodInstances(ctx, client, logs)
+		if err != nil {
+			return err
+		}
+	} else {
+		civoProvider, err := civo.NewProvider(true, logs)
+		if err != nil {
+			return err
+		}
+
+		instance, err := civo.GetDevpodInstance(ctx, civoProvider)
+		if err != nil {
+			return err
+		}
+
+		client = civoProvider.Client
+		instances = []civogo.Instance{*instance}
+	}
+
+	report, err := civo.GetCostReport(ctx, client, logs, instances, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if cmd.Output == "json" {
+		return report.PrintJSON(os.Stdout)
+	}
+
+	return report.Print(os.Stdout, cmd.All)
+}
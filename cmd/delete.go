@@ -19,14 +19,14 @@ func NewDeleteCmd() *cobra.Command {
 	deleteCmd := &cobra.Command{
 		Use:   "delete",
 		Short: "Delete an instance",
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
 			civoProvider, err := civo.NewProvider(true, log.Default)
 			if err != nil {
 				return err
 			}
 
 			return cmd.Run(
-				context.Background(),
+				cobraCmd.Context(),
 				civoProvider,
 				provider.FromEnvironment(),
 				log.Default,
@@ -45,5 +45,5 @@ func (cmd *DeleteCmd) Run(
 	logs log.Logger,
 ) error {
 
-	return civo.Delete(providerCivo)
+	return civo.Delete(ctx, providerCivo)
 }
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// GCCmd holds the cmd flags
+type GCCmd struct {
+	DryRun    bool
+	MinAge    time.Duration
+	Owner     string
+	AllOwners bool
+	Output    string
+}
+
+// NewGCCmd defines a command
+func NewGCCmd() *cobra.Command {
+	cmd := &GCCmd{}
+	gcCmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Delete the resources of DevPod machines that don't exist locally anymore",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(
+				cobraCmd.Context(),
+				log.Default,
+			)
+		},
+	}
+
+	gcCmd.Flags().BoolVar(&cmd.DryRun, "dry-run", false, "Only report the orphaned resources without deleting them")
+	gcCmd.Flags().DurationVar(&cmd.MinAge, "min-age", 24*time.Hour, "Skip machines created more recently than this")
+	gcCmd.Flags().StringVar(&cmd.Owner, "owner", "", "Only collect machines of this owner, defaults to CIVO_OWNER. Without either, the machines of your user name are only reported")
+	gcCmd.Flags().BoolVar(&cmd.AllOwners, "all-owners", false, "Collect machines of all owners, including ones without an owner. Their machines don't exist on this computer, so use with care")
+	gcCmd.Flags().StringVarP(&cmd.Output, "output", "o", "table", "The output format, one of table or json")
+	return gcCmd
+}
+
+// Run runs the command logic
+func (cmd *GCCmd) Run(
+	ctx context.Context,
+	logs log.Logger,
+) error {
+	if cmd.Output != "table" && cmd.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, use table or json", cmd.Output)
+	}
+
+	owner, dryRun := cmd.Owner, cmd.DryRun
+	if owner == "" {
+		owner = os.Getenv(options.CIVO_OWNER)
+	}
+	if owner == "" && !cmd.AllOwners {
+		// other people's machines can be tagged with the same user name
+		owner = options.OwnerFromEnv()
+		if !dryRun {
+			logs.Warnf("Only reporting the machines of owner %s, which is your user name. Set --owner or CIVO_OWNER to delete them", owner)
+			dryRun = true
+		}
+	}
+
+	client, err := civo.NewClient()
+	if err != nil {
+		return err
+	}
+
+	knownMachines, err := civo.LocalMachines()
+	if err != nil {
+		return err
+	}
+
+	report, gcErr := civo.CollectGarbage(ctx, client, logs, civo.GCOptions{
+		KnownMachines: knownMachines,
+		Owner:         owner,
+		AllOwners:     cmd.AllOwners,
+		MinAge:        cmd.MinAge,
+		DryRun:        dryRun,
+	})
+	if report == nil {
+		return gcErr
+	}
+
+	if cmd.Output == "json" {
+		err = report.PrintJSON(os.Stdout)
+	} else {
+		err = report.Print(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	return gcErr
+}
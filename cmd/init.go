@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
 	"github.com/loft-sh/devpod-provider-civo/pkg/options"
 	"github.com/loft-sh/devpod/pkg/log"
 	"github.com/loft-sh/devpod/pkg/provider"
@@ -21,10 +22,10 @@ func NewInitCmd() *cobra.Command {
 	initCmd := &cobra.Command{
 		Use:   "init",
 		Short: "Init account",
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
 
 			return cmd.Run(
-				context.Background(),
+				cobraCmd.Context(),
 				provider.FromEnvironment(),
 				log.Default,
 			)
@@ -50,16 +51,16 @@ func (cmd *InitCmd) Run(
 		return errors.Errorf("CIVO_REGION is not set")
 	}
 
-	_, err := options.FromEnv(true, true)
+	config, err := options.FromEnv(true, true)
 
 	if err != nil {
 		return err
 	}
 
-	_, err = civogo.NewClient(civoToken, civoRegion)
+	client, err := civogo.NewClient(civoToken, civoRegion)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return civo.Validate(ctx, client, config, logs)
 }
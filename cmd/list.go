@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// ListCmd holds the cmd flags
+type ListCmd struct {
+	Owner  string
+	Output string
+}
+
+// NewListCmd defines a command
+func NewListCmd() *cobra.Command {
+	cmd := &ListCmd{}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the DevPod instances of the account in all regions",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(
+				cobraCmd.Context(),
+				log.Default,
+			)
+		},
+	}
+
+	listCmd.Flags().StringVar(&cmd.Owner, "owner", "", "Only list instances of this owner")
+	listCmd.Flags().StringVarP(&cmd.Output, "output", "o", "table", "The output format, one of table or json")
+	return listCmd
+}
+
+// Run runs the command logic
+func (cmd *ListCmd) Run(
+	ctx context.Context,
+	logs log.Logger,
+) error {
+	if cmd.Output != "table" && cmd.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, use table or json", cmd.Output)
+	}
+
+	client, err := civo.NewClient()
+	if err != nil {
+		return err
+	}
+
+	entries, err := civo.ListMachines(ctx, client, logs, cmd.Owner)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Output == "json" {
+		return civo.PrintMachinesJSON(os.Stdout, entries)
+	}
+
+	return civo.PrintMachines(os.Stdout, entries)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// ListOptionsCmd holds the cmd flags
+type ListOptionsCmd struct {
+	Output   string
+	CacheTTL time.Duration
+}
+
+// NewListOptionsCmd defines a command
+func NewListOptionsCmd() *cobra.Command {
+	cmd := &ListOptionsCmd{}
+	listOptionsCmd := &cobra.Command{
+		Use:   "list-options",
+		Short: "List the regions, instance types and disk images available to the account",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return cmd.Run(
+				cobraCmd.Context(),
+				log.Default,
+			)
+		},
+	}
+
+	listOptionsCmd.Flags().StringVarP(&cmd.Output, "output", "o", "json", "The output format, one of json or table")
+	listOptionsCmd.Flags().DurationVar(&cmd.CacheTTL, "cache-ttl", time.Hour, "How long to cache the options locally, 0 disables the cache")
+	return listOptionsCmd
+}
+
+// Run runs the command logic
+func (cmd *ListOptionsCmd) Run(
+	ctx context.Context,
+	logs log.Logger,
+) error {
+	client, err := civo.NewClient()
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := civo.GetSuggestions(ctx, client, cmd.CacheTTL, logs)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Output {
+	case "json":
+		out, err := json.MarshalIndent(suggestions.ProviderOptions(), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(out))
+		return nil
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "OPTION\tVALUE\tDESCRIPTION")
+		for _, name := range []string{options.CIVO_REGION, options.CIVO_INSTANCE_TYPE, options.CIVO_DISK_IMAGE} {
+			for _, value := range suggestions.Options[name] {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", name, value.Value, value.DisplayName)
+			}
+		}
+
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, use json or table", cmd.Output)
+	}
+}
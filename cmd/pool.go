@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// defaultPoolTTL is how long pool instances stay idle before they are reaped.
+const defaultPoolTTL = 24 * time.Hour
+
+// NewPoolCmd defines a command
+func NewPoolCmd() *cobra.Command {
+	poolCmd := &cobra.Command{
+		Use:   "pool",
+		Short: "Manage the warm pool of instances claimed by create when CIVO_POOL is enabled",
+	}
+
+	poolCmd.AddCommand(NewPoolFillCmd())
+	poolCmd.AddCommand(NewPoolReapCmd())
+	return poolCmd
+}
+
+// PoolFillCmd holds the cmd flags
+type PoolFillCmd struct {
+	Size int
+	TTL  time.Duration
+}
+
+// NewPoolFillCmd defines a command
+func NewPoolFillCmd() *cobra.Command {
+	cmd := &PoolFillCmd{}
+	poolFillCmd := &cobra.Command{
+		Use:   "fill",
+		Short: "Create idle instances with the current options until the pool has the given size",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			civoProvider, err := civo.NewAccountProvider(log.Default)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(
+				cobraCmd.Context(),
+				civoProvider,
+				log.Default,
+			)
+		},
+	}
+
+	poolFillCmd.Flags().IntVar(&cmd.Size, "size", 1, "The number of idle instances to keep in the pool")
+	poolFillCmd.Flags().DurationVar(&cmd.TTL, "ttl", defaultPoolTTL, "Delete pool instances that were idle for longer than this")
+	return poolFillCmd
+}
+
+// Run runs the command logic
+func (cmd *PoolFillCmd) Run(
+	ctx context.Context,
+	providerCivo *civo.CivoProvider,
+	logs log.Logger,
+) error {
+	if cmd.Size < 0 {
+		return fmt.Errorf("invalid pool size %d, must not be negative", cmd.Size)
+	}
+
+	created, err := civo.FillPool(ctx, providerCivo, cmd.Size, cmd.TTL)
+	if err != nil {
+		return err
+	}
+
+	logs.Infof("Created %d pool instances", created)
+	return nil
+}
+
+// PoolReapCmd holds the cmd flags
+type PoolReapCmd struct {
+	TTL time.Duration
+}
+
+// NewPoolReapCmd defines a command
+func NewPoolReapCmd() *cobra.Command {
+	cmd := &PoolReapCmd{}
+	poolReapCmd := &cobra.Command{
+		Use:   "reap",
+		Short: "Delete pool instances that were idle for longer than the ttl",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			civoProvider, err := civo.NewAccountProvider(log.Default)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(
+				cobraCmd.Context(),
+				civoProvider,
+				log.Default,
+			)
+		},
+	}
+
+	poolReapCmd.Flags().DurationVar(&cmd.TTL, "ttl", defaultPoolTTL, "Delete pool instances that were idle for longer than this")
+	return poolReapCmd
+}
+
+// Run runs the command logic
+func (cmd *PoolReapCmd) Run(
+	ctx context.Context,
+	providerCivo *civo.CivoProvider,
+	logs log.Logger,
+) error {
+	reaped, err := civo.ReapPool(ctx, providerCivo, cmd.TTL)
+	if err != nil {
+		return err
+	}
+
+	logs.Infof("Deleted %d pool instances", reaped)
+	return nil
+}
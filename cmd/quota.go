@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// QuotaCmd holds the cmd flags
+type QuotaCmd struct{}
+
+// NewQuotaCmd defines a command
+func NewQuotaCmd() *cobra.Command {
+	cmd := &QuotaCmd{}
+	quotaCmd := &cobra.Command{
+		Use:   "quota",
+		Short: "Show the account quota needed to create an instance",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			civoProvider, err := civo.NewAccountProvider(log.Default)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(
+				cobraCmd.Context(),
+				civoProvider,
+				log.Default,
+			)
+		},
+	}
+
+	return quotaCmd
+}
+
+// Run runs the command logic
+func (cmd *QuotaCmd) Run(
+	ctx context.Context,
+	providerCivo *civo.CivoProvider,
+	logs log.Logger,
+) error {
+	report, err := civo.GetQuotaReport(ctx, providerCivo)
+	if err != nil {
+		return err
+	}
+
+	return report.Print(os.Stdout)
+}
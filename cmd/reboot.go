@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// RebootCmd holds the cmd flags
+type RebootCmd struct {
+	Hard bool
+}
+
+// NewRebootCmd defines a command
+func NewRebootCmd() *cobra.Command {
+	cmd := &RebootCmd{}
+	rebootCmd := &cobra.Command{
+		Use:   "reboot",
+		Short: "Reboot an instance",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			civoProvider, err := civo.NewProvider(true, log.Default)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(
+				cobraCmd.Context(),
+				civoProvider,
+				log.Default,
+			)
+		},
+	}
+
+	rebootCmd.Flags().BoolVar(&cmd.Hard, "hard", false, "Reset the instance instead of rebooting its operating system, for instances that don't respond anymore")
+	return rebootCmd
+}
+
+// Run runs the command logic
+func (cmd *RebootCmd) Run(
+	ctx context.Context,
+	providerCivo *civo.CivoProvider,
+	logs log.Logger,
+) error {
+	return civo.Reboot(ctx, providerCivo, cmd.Hard)
+}
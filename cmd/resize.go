@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/loft-sh/devpod-provider-civo/pkg/civo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// ResizeCmd holds the cmd flags
+type ResizeCmd struct{}
+
+// NewResizeCmd defines a command
+func NewResizeCmd() *cobra.Command {
+	cmd := &ResizeCmd{}
+	resizeCmd := &cobra.Command{
+		Use:   "resize SIZE",
+		Short: "Change the instance size of the machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			civoProvider, err := civo.NewProvider(true, log.Default)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(
+				cobraCmd.Context(),
+				civoProvider,
+				args[0],
+				log.Default,
+			)
+		},
+	}
+
+	return resizeCmd
+}
+
+// Run runs the command logic
+func (cmd *ResizeCmd) Run(
+	ctx context.Context,
+	providerCivo *civo.CivoProvider,
+	size string,
+	logs log.Logger,
+) error {
+	return civo.Resize(ctx, providerCivo, size)
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
 
 	"github.com/loft-sh/devpod/pkg/log"
 	"github.com/spf13/cobra"
@@ -32,8 +35,17 @@ func Execute() {
 	// build the root command
 	rootCmd := BuildRoot()
 
+	// cancel running operations on interrupt, so they can clean up. A second
+	// interrupt exits right away, e.g. when the clean up hangs.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	// execute command
-	err := rootCmd.Execute()
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
 		if exitErr, ok := err.(*ssh.ExitError); ok {
 			os.Exit(exitErr.ExitStatus())
@@ -61,5 +73,14 @@ func BuildRoot() *cobra.Command {
 	rootCmd.AddCommand(NewStopCmd())
 	rootCmd.AddCommand(NewStatusCmd())
 	rootCmd.AddCommand(NewTokenCmd())
+	rootCmd.AddCommand(NewQuotaCmd())
+	rootCmd.AddCommand(NewListOptionsCmd())
+	rootCmd.AddCommand(NewCostCmd())
+	rootCmd.AddCommand(NewPoolCmd())
+	rootCmd.AddCommand(NewResizeCmd())
+	rootCmd.AddCommand(NewRebootCmd())
+	rootCmd.AddCommand(NewConsoleCmd())
+	rootCmd.AddCommand(NewGCCmd())
+	rootCmd.AddCommand(NewListCmd())
 	return rootCmd
 }
@@ -18,14 +18,14 @@ func NewStartCmd() *cobra.Command {
 	startCmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start an instance",
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
 			civoProvider, err := civo.NewProvider(true, log.Default)
 			if err != nil {
 				return err
 			}
 
 			return cmd.Run(
-				context.Background(),
+				cobraCmd.Context(),
 				civoProvider,
 				provider.FromEnvironment(),
 				log.Default,
@@ -44,5 +44,5 @@ func (cmd *StartCmd) Run(
 	logs log.Logger,
 ) error {
 
-	return civo.Start(providerCivo)
+	return civo.Start(ctx, providerCivo)
 }
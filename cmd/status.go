@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -12,21 +13,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-type InstanceStatus struct {
-	NetworkInterfaces []InstanceStatusNetworkInterface `json:"networkInterfaces,omitempty"`
-	Status            string                           `json:"status,omitempty"`
-}
-
-type InstanceStatusNetworkInterface struct {
-	AccessConfigs []InstanceStatusAccessConfig `json:"accessConfigs,omitempty"`
-}
-
-type InstanceStatusAccessConfig struct {
-	NatIP string `json:"natIP,omitempty"`
-}
-
 // StatusCmd holds the cmd flags
-type StatusCmd struct{}
+type StatusCmd struct {
+	Output string
+}
 
 // NewStatusCmd defines a command
 func NewStatusCmd() *cobra.Command {
@@ -34,14 +24,14 @@ func NewStatusCmd() *cobra.Command {
 	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: "Status an instance",
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
 			civoProvider, err := civo.NewProvider(true, log.Default)
 			if err != nil {
 				return err
 			}
 
 			return cmd.Run(
-				context.Background(),
+				cobraCmd.Context(),
 				civoProvider,
 				provider.FromEnvironment(),
 				log.Default,
@@ -49,6 +39,7 @@ func NewStatusCmd() *cobra.Command {
 		},
 	}
 
+	statusCmd.Flags().StringVarP(&cmd.Output, "output", "o", "plain", "The output format, one of plain or json")
 	return statusCmd
 }
 
@@ -59,12 +50,29 @@ func (cmd *StatusCmd) Run(
 	machine *provider.Machine,
 	logs log.Logger,
 ) error {
+	switch cmd.Output {
+	case "plain":
+		status, err := civo.Status(ctx, providerCivo)
+		if err != nil {
+			return err
+		}
 
-	status, err := civo.Status(providerCivo)
-	if err != nil {
+		_, err = fmt.Fprint(os.Stdout, status)
 		return err
-	}
+	case "json":
+		details, err := civo.GetInstanceDetails(ctx, providerCivo)
+		if err != nil {
+			return err
+		}
 
-	_, err = fmt.Fprint(os.Stdout, status)
-	return err
+		out, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(out))
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, use plain or json", cmd.Output)
+	}
 }
@@ -19,14 +19,14 @@ func NewStopCmd() *cobra.Command {
 	stopCmd := &cobra.Command{
 		Use:   "stop",
 		Short: "Stop an instance",
-		RunE: func(_ *cobra.Command, args []string) error {
-			civoProvider, err := civo.NewProvider(false, log.Default)
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			civoProvider, err := civo.NewProvider(true, log.Default)
 			if err != nil {
 				return err
 			}
 
 			return cmd.Run(
-				context.Background(),
+				cobraCmd.Context(),
 				civoProvider,
 				provider.FromEnvironment(),
 				log.Default,
@@ -45,5 +45,5 @@ func (cmd *StopCmd) Run(
 	logs log.Logger,
 ) error {
 
-	return civo.Stop(providerCivo)
+	return civo.Stop(ctx, providerCivo)
 }
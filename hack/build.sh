@@ -20,7 +20,7 @@ if [[ "$(pwd)" != "${PROVIDER_ROOT}" ]]; then
 fi
 
 GO_BUILD_CMD="go build"
-GO_BUILD_LDFLAGS="-s -w"
+GO_BUILD_LDFLAGS="-s -w -X github.com/loft-sh/devpod-provider-civo/pkg/version.Version=${RELEASE_VERSION}"
 
 if [[ -z "${PROVIDER_BUILD_PLATFORMS}" ]]; then
     PROVIDER_BUILD_PLATFORMS="linux windows darwin"
@@ -59,5 +59,27 @@ for OS in ${PROVIDER_BUILD_PLATFORMS[@]}; do
   done
 done
 
+if [[ -z "${PROVIDER_SUGGESTION_REGIONS}" ]]; then
+    PROVIDER_SUGGESTION_REGIONS="FRA1 LON1 NYC1 PHX1"
+fi
+
+# list the option suggestions of each region, which are added to the static
+# ones in provider.yaml. The files aren't released, so they go to a temp dir.
+OPTIONS_DIR=$(mktemp -d)
+trap 'rm -rf "${OPTIONS_DIR}"' EXIT
+OPTIONS_FILES=()
+if [[ -n "${CIVO_API_KEY}" ]]; then
+  for REGION in ${PROVIDER_SUGGESTION_REGIONS[@]}; do
+    OPTIONS_FILE="${OPTIONS_DIR}/options-${REGION}.json"
+    if CIVO_REGION=${REGION} go run -mod vendor "${PROVIDER_ROOT}/main.go" list-options --cache-ttl 0 > "${OPTIONS_FILE}"; then
+      OPTIONS_FILES+=("${OPTIONS_FILE}")
+    else
+      echo "Listing the options of region ${REGION} failed, provider.yaml only has the static suggestions for it" 1>&2
+    fi
+  done
+else
+  echo "CIVO_API_KEY is not set, provider.yaml only has the static option suggestions"
+fi
+
 # generate provider.yaml
-go run -mod vendor "${PROVIDER_ROOT}/hack/provider/main.go" ${RELEASE_VERSION} > "${PROVIDER_ROOT}/release/provider.yaml"
+go run -mod vendor "${PROVIDER_ROOT}/hack/provider/main.go" ${RELEASE_VERSION} "${OPTIONS_FILES[@]}" > "${PROVIDER_ROOT}/release/provider.yaml"
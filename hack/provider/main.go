@@ -3,12 +3,28 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strings"
 )
 
+// suggestionsPattern matches the placeholders for the suggestions of an option,
+// which are filled in from staticSuggestions and the output of
+// "devpod-provider-civo list-options".
+var suggestionsPattern = regexp.MustCompile(`##SUGGESTIONS_([A-Z0-9_]+)##`)
+
+// staticSuggestions are released even if the values can't be listed from the
+// api, the values listed for each region are added to them. The disk image
+// default is an ID, so it's suggested as well.
+var staticSuggestions = map[string][]string{
+	"CIVO_REGION":        {"FRA1", "LON1", "NYC1", "PHX1"},
+	"CIVO_INSTANCE_TYPE": {"g3.small", "g3.medium", "g3.large", "g3.xlarge", "g3.2xlarge"},
+	"CIVO_DISK_IMAGE":    {"d927ad2f-5073-4ed6-b2eb-b8e61aef29a8"},
+}
+
 var checksumMap = map[string]string{
 	"./release/devpod-provider-civo-linux-amd64":       "##CHECKSUM_LINUX_AMD64##",
 	"./release/devpod-provider-civo-linux-arm64":       "##CHECKSUM_LINUX_ARM64##",
@@ -18,8 +34,8 @@ var checksumMap = map[string]string{
 }
 
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintln(os.Stderr, "Expected version as argument")
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Expected version and optionally list-options output files as arguments")
 		os.Exit(1)
 		return
 	}
@@ -39,9 +55,80 @@ func main() {
 		replaced = strings.Replace(replaced, v, checksum, -1)
 	}
 
+	suggestions := map[string][]string{}
+	for name, values := range staticSuggestions {
+		suggestions[name] = merge(suggestions[name], values)
+	}
+
+	for _, optionsFile := range os.Args[2:] {
+		listed, err := Suggestions(optionsFile)
+		if err != nil {
+			panic(fmt.Errorf("read suggestions from %s: %v", optionsFile, err))
+		}
+
+		for name, values := range listed {
+			suggestions[name] = merge(suggestions[name], values)
+		}
+	}
+
+	replaced = suggestionsPattern.ReplaceAllStringFunc(replaced, func(placeholder string) string {
+		values := suggestions[suggestionsPattern.FindStringSubmatch(placeholder)[1]]
+		if values == nil {
+			values = []string{}
+		}
+
+		// a json array is a yaml flow sequence
+		out, _ := json.Marshal(values)
+		return string(out)
+	})
+
 	fmt.Print(replaced)
 }
 
+// Suggestions reads the suggestions per option from the list-options output
+func Suggestions(filePath string) (map[string][]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	listOptions := struct {
+		Options map[string]struct {
+			Suggestions []string `json:"suggestions"`
+		} `json:"options"`
+	}{}
+	err = json.Unmarshal(content, &listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := map[string][]string{}
+	for name, option := range listOptions.Options {
+		suggestions[name] = option.Suggestions
+	}
+
+	return suggestions, nil
+}
+
+// merge appends the values that aren't in existing yet
+func merge(existing, values []string) []string {
+	for _, value := range values {
+		found := false
+		for _, e := range existing {
+			if e == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			existing = append(existing, value)
+		}
+	}
+
+	return existing
+}
+
 // File hashes a given file to a sha256 string
 func File(filePath string) (string, error) {
 	file, err := os.Open(filePath)
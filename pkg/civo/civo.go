@@ -1,25 +1,64 @@
 package civo
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/loft-sh/devpod-provider-civo/pkg/options"
 	"github.com/loft-sh/devpod/pkg/client"
 	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/loft-sh/devpod/pkg/ssh"
 
 	"github.com/civo/civogo"
 	"github.com/pkg/errors"
 )
 
 type CivoToken struct {
-	APIKey string "json:apikey"
-	Region string "json:region"
+	APIKey string `json:"apikey"`
+	Region string `json:"region"`
 }
 
 var tokenJSON CivoToken
 
 func NewProvider(withFolder bool, logs log.Logger) (*CivoProvider, error) {
+	return newProvider(false, withFolder, logs)
+}
+
+// NewAccountProvider creates a provider for account wide commands, which don't
+// need a machine.
+func NewAccountProvider(logs log.Logger) (*CivoProvider, error) {
+	return newProvider(true, false, logs)
+}
+
+func newProvider(init, withFolder bool, logs log.Logger) (*CivoProvider, error) {
+	client, err := NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := options.FromEnv(init, withFolder)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// create provider
+	provider := &CivoProvider{
+		Config: config,
+		Client: client,
+		Log:    logs,
+	}
+
+	return provider, nil
+}
+
+// NewClient creates a civo client from CIVO_TOKEN or CIVO_API_KEY and
+// CIVO_REGION, without requiring any other provider option.
+func NewClient() (*civogo.Client, error) {
 	civoToken := os.Getenv("CIVO_TOKEN")
 	if civoToken != "" {
 		err := json.Unmarshal([]byte(civoToken), &tokenJSON)
@@ -48,34 +87,26 @@ func NewProvider(withFolder bool, logs log.Logger) (*CivoProvider, error) {
 		return nil, errors.Errorf("CIVO_REGION is not set")
 	}
 
-	config, err := options.FromEnv(false, withFolder)
-
-	if err != nil {
-		return nil, err
-	}
-
-	client, err := civogo.NewClient(civoApiKey, civoRegion)
-	if err != nil {
-		return nil, err
-	}
-
-	// create provider
-	provider := &CivoProvider{
-		Config: config,
-		Client: client,
-		Log:    logs,
-	}
-
-	return provider, nil
+	return civogo.NewClient(civoApiKey, civoRegion)
 }
 
+// CivoProvider holds the options of the machine and the civo client. The
+// client is a civogo.Client, or a civogo.FakeClient in tests.
 type CivoProvider struct {
 	Config           *options.Options
-	Client           *civogo.Client
+	Client           civogo.Clienter
 	Log              log.Logger
 	WorkingDirectory string
 }
 
+// setRegion switches the provider to the region, the fake client has no region.
+func setRegion(civoProvider *CivoProvider, region string) {
+	civoProvider.Config.Region = region
+	if client, ok := civoProvider.Client.(*civogo.Client); ok {
+		client.Region = region
+	}
+}
+
 func AccessToken() (string, error) {
 	// If the user is logged via token, just forward it
 	civoToken := os.Getenv("CIVO_TOKEN")
@@ -101,66 +132,618 @@ func AccessToken() (string, error) {
 	return string(result), err
 }
 
-func GetDevpodInstance(civoProvider *CivoProvider) (*civogo.Instance, error) {
-	return civoProvider.Client.FindInstance(civoProvider.Config.MachineID)
+const listInstancesPerPage = 100
+
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// GetDevpodInstance looks the instance up by the ID recorded in the machine
+// folder and falls back to the machine tag if there is no state or the
+// recorded instance doesn't exist anymore.
+func GetDevpodInstance(ctx context.Context, civoProvider *CivoProvider) (*civogo.Instance, error) {
+	state, err := LoadMachineState(civoProvider.Config.MachineFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	if state != nil && state.InstanceID != "" {
+		if state.Region != "" {
+			setRegion(civoProvider, state.Region)
+		}
+
+		instance, err := retryValue(ctx, civoProvider.Log, "get instance", func() (*civogo.Instance, error) {
+			return civoProvider.Client.GetInstance(state.InstanceID)
+		})
+		if !IsNotFound(err) {
+			return instance, err
+		}
+
+		civoProvider.Log.Debugf("Instance %s recorded for the machine doesn't exist anymore", state.InstanceID)
+	}
+
+	return findInstanceByHostname(ctx, civoProvider)
+}
+
+func findInstanceByHostname(ctx context.Context, civoProvider *CivoProvider) (*civogo.Instance, error) {
+	matches, err := findMachineInstances(ctx, civoProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, errors.Wrapf(ErrInstanceNotFound, "find instance %s", civoProvider.Config.MachineID)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, errors.Errorf("found %d instances with hostname %s", len(matches), civoProvider.Config.MachineID)
+	}
+}
+
+// findMachineInstances returns all instances of the machine, which are more
+// than one only if creates ran concurrently.
+func findMachineInstances(ctx context.Context, civoProvider *CivoProvider) ([]civogo.Instance, error) {
+	instances, err := listAllInstances(ctx, civoProvider.Client, civoProvider.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := []civogo.Instance{}
+	untagged := []civogo.Instance{}
+	for _, instance := range instances {
+		if hasTag(instance.Tags, MachineTag(civoProvider.Config.MachineID)) {
+			tagged = append(tagged, instance)
+		} else if instance.Hostname == civoProvider.Config.MachineID && IsDevpodInstance(&instance) {
+			tagged = append(tagged, instance)
+		} else if instance.Hostname == civoProvider.Config.MachineID {
+			untagged = append(untagged, instance)
+		}
+	}
+
+	// instances created before tagging was introduced only match by hostname
+	if len(tagged) == 0 {
+		return untagged, nil
+	}
+
+	return tagged, nil
+}
+
+// ListDevpodInstances returns all instances in the client's region that were
+// created by the provider, including ones from before tagging was introduced.
+func ListDevpodInstances(ctx context.Context, client civogo.Clienter, logs log.Logger) ([]civogo.Instance, error) {
+	instances, err := listAllInstances(ctx, client, logs)
+	if err != nil {
+		return nil, err
+	}
+
+	devpodInstances := []civogo.Instance{}
+	for _, instance := range instances {
+		if IsDevpodInstance(&instance) || strings.HasPrefix(instance.Hostname, options.MachineIDPrefix) {
+			devpodInstances = append(devpodInstances, instance)
+		}
+	}
+
+	return devpodInstances, nil
+}
+
+func listAllInstances(ctx context.Context, client civogo.Clienter, logs log.Logger) ([]civogo.Instance, error) {
+	all := []civogo.Instance{}
+	for page := 1; ; page++ {
+		instances, err := retryValue(ctx, logs, "list instances", func() (*civogo.PaginatedInstanceList, error) {
+			return client.ListInstances(page, listInstancesPerPage)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, instances.Items...)
+		if page >= instances.Pages {
+			return all, nil
+		}
+	}
 }
 
-func Create(civoProvider *CivoProvider) error {
+// InitialUser is the user civo creates on the instance and authorizes the
+// uploaded DevPod machine key for.
+const InitialUser = "civo"
+
+func GetDevpodSSHKey(ctx context.Context, civoProvider *CivoProvider) (*civogo.SSHKey, error) {
+	sshKeys, err := listDevpodSSHKeys(ctx, civoProvider)
+	if err != nil || len(sshKeys) == 0 {
+		return nil, err
+	}
+
+	return &sshKeys[0], nil
+}
+
+// listDevpodSSHKeys returns the ssh keys uploaded for the machine, which are
+// more than one only if creates ran concurrently.
+func listDevpodSSHKeys(ctx context.Context, civoProvider *CivoProvider) ([]civogo.SSHKey, error) {
+	sshKeys, err := retryValue(ctx, civoProvider.Log, "list ssh keys", civoProvider.Client.ListSSHKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	machineKeys := []civogo.SSHKey{}
+	for _, sshKey := range sshKeys {
+		if sshKey.Name == civoProvider.Config.MachineID {
+			machineKeys = append(machineKeys, sshKey)
+		}
+	}
+
+	return machineKeys, nil
+}
+
+func ensureSSHKey(ctx context.Context, civoProvider *CivoProvider, journal *createJournal) (string, error) {
+	publicKeyBase, err := ssh.GetPublicKeyBase(civoProvider.Config.MachineFolder)
+	if err != nil {
+		return "", errors.Wrap(err, "get public key")
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(publicKeyBase)
+	if err != nil {
+		return "", errors.Wrap(err, "decode public key")
+	}
+
+	sshKey, err := GetDevpodSSHKey(ctx, civoProvider)
+	if err != nil {
+		return "", errors.Wrap(err, "find ssh key")
+	}
+
+	if sshKey != nil {
+		if strings.TrimSpace(sshKey.PublicKey) == strings.TrimSpace(string(publicKey)) {
+			return sshKey.ID, nil
+		}
+
+		// the machine folder was recreated, replace the stale key
+		err = retry(ctx, civoProvider.Log, "delete ssh key", func() error {
+			_, err := civoProvider.Client.DeleteSSHKey(sshKey.ID)
+			return err
+		})
+		if err != nil {
+			return "", errors.Wrap(err, "delete stale ssh key")
+		}
+	}
+
+	sshKeyID, err := createOnce(ctx, civoProvider.Log, "ssh key",
+		func() (string, error) {
+			result, err := civoProvider.Client.NewSSHKey(civoProvider.Config.MachineID, strings.TrimSpace(string(publicKey)))
+			if err != nil {
+				return "", err
+			}
+
+			return result.ID, nil
+		},
+		func() (string, bool, error) {
+			sshKey, err := GetDevpodSSHKey(ctx, civoProvider)
+			if err != nil || sshKey == nil {
+				return "", false, err
+			}
+
+			return sshKey.ID, true, nil
+		},
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "upload ssh key")
+	}
+
+	journal.record("ssh key "+civoProvider.Config.MachineID, func(ctx context.Context) error {
+		return deleteCreatedSSHKey(ctx, civoProvider, sshKeyID)
+	})
+	return sshKeyID, nil
+}
+
+// deleteSSHKey deletes the ssh keys uploaded for the machine, if any.
+func deleteSSHKey(ctx context.Context, civoProvider *CivoProvider) error {
+	sshKeys, err := listDevpodSSHKeys(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	for _, sshKey := range sshKeys {
+		err = removeSSHKey(ctx, civoProvider, sshKey.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteCreatedSSHKey deletes the ssh key uploaded by a failed or duplicate
+// create. It's looked up by ID, so the key of a concurrent create is kept.
+func deleteCreatedSSHKey(ctx context.Context, civoProvider *CivoProvider, sshKeyID string) error {
+	return removeSSHKey(ctx, civoProvider, sshKeyID)
+}
+
+func removeSSHKey(ctx context.Context, civoProvider *CivoProvider, sshKeyID string) error {
+	err := retry(ctx, civoProvider.Log, "delete ssh key", func() error {
+		_, err := civoProvider.Client.DeleteSSHKey(sshKeyID)
+		return err
+	})
+	if err != nil && !IsNotFound(err) {
+		return errors.Wrap(err, "delete ssh key")
+	}
+
+	return nil
+}
+
+// Create creates the instance and everything it needs. If that fails or the
+// context is cancelled, the resources created so far are removed again.
+func Create(ctx context.Context, civoProvider *CivoProvider) error {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if err == nil {
+		return adoptInstance(ctx, civoProvider, instance)
+	} else if !IsNotFound(err) {
+		return err
+	}
+
+	journal := &createJournal{}
+
+	err = create(ctx, civoProvider, journal)
+	if err != nil {
+		return journal.rollback(civoProvider, err)
+	}
+
+	return nil
+}
+
+func create(ctx context.Context, civoProvider *CivoProvider, journal *createJournal) error {
+	if civoProvider.Config.Pool {
+		claimed, err := claimFromPool(ctx, civoProvider, journal)
+		if err != nil || claimed {
+			return err
+		}
+	}
+
+	err := checkQuota(ctx, civoProvider, 1)
+	if err != nil {
+		return err
+	}
+
+	sshKeyID, err := ensureSSHKey(ctx, civoProvider, journal)
+	if err != nil {
+		return err
+	}
+
+	image, err := ResolveDiskImage(ctx, civoProvider.Client, civoProvider.Log, civoProvider.Config.DiskImage, civoProvider.Config.Region)
+	if err != nil {
+		return err
+	}
+
+	volumeSizeGB, err := dataVolumeSizeGB(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
 
-	config, err := civoProvider.Client.NewInstanceConfig()
+	config, err := retryValue(ctx, civoProvider.Log, "get instance defaults", civoProvider.Client.NewInstanceConfig)
 	if err != nil {
 		return err
 	}
 
 	config.PublicIPRequired = "true"
+	if !civoProvider.Config.PublicIP {
+		config.PublicIPRequired = "none"
+	}
 	config.Count = 1
 	config.Hostname = civoProvider.Config.MachineID
-	config.Size = civoProvider.Config.MachineType
+	config.Size, err = machineSize(civoProvider)
+	if err != nil {
+		return err
+	}
 	config.Region = civoProvider.Config.Region
-	config.PublicIPRequired = "true"
+	config.InitialUser = InitialUser
+	config.SSHKeyID = sshKeyID
+	config.TemplateID = image.ID
+	config.Tags = instanceTags(civoProvider)
 
-	_, err = civoProvider.Client.CreateInstance(config)
+	config.NetworkID, err = resolveNetwork(ctx, civoProvider, config.NetworkID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	config.FirewallID, err = ensureFirewall(ctx, civoProvider, config.NetworkID, journal)
+	if err != nil {
+		return err
+	}
+
+	config.Script, err = RenderScript(machineScriptData(civoProvider, volumeSizeGB > 0))
+	if err != nil {
+		return err
+	}
+
+	instance, err := createOnce(ctx, civoProvider.Log, "instance",
+		func() (*civogo.Instance, error) {
+			return civoProvider.Client.CreateInstance(config)
+		},
+		func() (*civogo.Instance, bool, error) {
+			instance, err := findInstanceByHostname(ctx, civoProvider)
+			if errors.Is(err, ErrInstanceNotFound) {
+				return nil, false, nil
+			} else if err != nil {
+				return nil, false, err
+			}
+
+			return instance, true, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	createdID := instance.ID
+	journal.record("instance "+civoProvider.Config.MachineID, func(ctx context.Context) error {
+		return deleteCreatedInstance(ctx, civoProvider, createdID)
+	})
+
+	winner, err := resolveDuplicateInstances(ctx, civoProvider, instance)
+	if err != nil {
+		return err
+	} else if winner.ID != instance.ID {
+		civoProvider.Log.Infof("Instance %s was created concurrently, deleting %s and using it instead", winner.ID, instance.ID)
+
+		// remove the instance, firewall and ssh key this create made, the
+		// concurrent create launched its instance with its own
+		failed := journal.undo(ctx, civoProvider)
+		if len(failed) > 0 {
+			return fmt.Errorf("remove the duplicate %s", strings.Join(failed, ", "))
+		}
+
+		return adoptInstance(ctx, civoProvider, winner)
+	}
+
+	err = saveInstanceSize(civoProvider, instance.ID, config.Size)
+	if err != nil {
+		return err
+	}
+
+	instance, err = WaitForInstance(ctx, civoProvider.Client, instance.ID, waitOptions(civoProvider), civoProvider.Log)
+	if err != nil {
+		return err
+	}
+
+	instance.Notes = instanceNotes(civoProvider)
+	err = retry(ctx, civoProvider.Log, "update instance", func() error {
+		_, err := civoProvider.Client.UpdateInstance(instance)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "update instance notes")
+	}
+
+	if volumeSizeGB > 0 {
+		err = attachDataVolume(ctx, civoProvider, instance.ID, config.NetworkID, volumeSizeGB, journal)
+		if err != nil {
+			return err
+		}
+	}
+
+	if civoProvider.Config.ReservedIP != "" {
+		err = assignReservedIP(ctx, civoProvider, instance.ID, journal)
+		if err != nil {
+			return err
+		}
+
+		// wait until the instance is reachable at the reserved ip
+		instance, err = WaitForInstance(ctx, civoProvider.Client, instance.ID, waitOptions(civoProvider), civoProvider.Log)
+		if err != nil {
+			return err
+		}
+	}
+
+	return waitForStartupScript(ctx, civoProvider, instance)
+}
+
+// machineScriptData returns the startup script variables of the machine.
+func machineScriptData(civoProvider *CivoProvider, dataDisk bool) ScriptData {
+	return ScriptData{
+		MachineID:         civoProvider.Config.MachineID,
+		WorkspaceID:       civoProvider.Config.WorkspaceID,
+		Region:            civoProvider.Config.Region,
+		InitialUser:       InitialUser,
+		AgentPath:         civoProvider.Config.AgentPath,
+		DataDisk:          dataDisk,
+		DataDiskMountPath: DataDiskMountPath,
+		PersistAgentDir:   civoProvider.Config.StopMode == options.StopModeHibernate,
+		UserScript:        civoProvider.Config.InitScript,
+	}
 }
-func Delete(civoProvider *CivoProvider) error {
-	instance, err := GetDevpodInstance(civoProvider)
+
+// Delete deletes the instance and the resources created for it, and waits
+// until they are gone. Resources that don't exist anymore are skipped, so
+// deleting a machine again succeeds.
+func Delete(ctx context.Context, civoProvider *CivoProvider) error {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	err = detachDataVolume(ctx, civoProvider)
 	if err != nil {
 		return err
 	}
 
-	_, err = civoProvider.Client.DeleteInstance(instance.ID)
+	instanceID := ""
+	if instance != nil {
+		instanceID = instance.ID
+	}
+
+	err = unassignReservedIP(ctx, civoProvider, instanceID, civoProvider.Config.ReleaseReservedIP)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if instance != nil {
+		err = deleteInstance(ctx, civoProvider, instance.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !keepDataVolume(civoProvider) {
+		err = deleteDataVolume(ctx, civoProvider)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = deleteFirewall(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	err = deleteSSHKey(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	return DeleteMachineState(civoProvider.Config.MachineFolder)
 }
 
-func Start(civoProvider *CivoProvider) error {
-	instance, err := GetDevpodInstance(civoProvider)
+// deleteInstance deletes the instance and waits until it is gone, so the
+// firewall and volume can be deleted afterwards.
+func deleteInstance(ctx context.Context, civoProvider *CivoProvider, instanceID string) error {
+	err := retry(ctx, civoProvider.Log, "delete instance", func() error {
+		_, err := civoProvider.Client.DeleteInstance(instanceID)
+		return err
+	})
+	if err != nil && !IsNotFound(err) {
+		return errors.Wrapf(err, "delete instance %s", instanceID)
+	}
+
+	return WaitForInstanceDeleted(ctx, civoProvider.Client, instanceID, waitOptions(civoProvider), civoProvider.Log)
+}
+
+// deleteCreatedInstance removes the instance of a failed create.
+func deleteCreatedInstance(ctx context.Context, civoProvider *CivoProvider, instanceID string) error {
+	err := deleteInstance(ctx, civoProvider, instanceID)
 	if err != nil {
 		return err
 	}
 
-	_, err = civoProvider.Client.StartInstance(instance.ID)
+	// keep the recorded size, a failed resume of a resized machine retries
+	// with it
+	return updateMachineState(civoProvider.Config.MachineFolder, func(state *MachineState) {
+		if state.InstanceID == instanceID {
+			state.InstanceID = ""
+		}
+	})
+}
+
+// adoptInstance uses an instance that already exists for the machine instead
+// of creating another one, starting it if it is stopped.
+func adoptInstance(ctx context.Context, civoProvider *CivoProvider, instance *civogo.Instance) error {
+	civoProvider.Log.Infof("Instance %s already exists for machine %s, reusing it", instance.ID, civoProvider.Config.MachineID)
+
+	err := saveInstanceSize(civoProvider, instance.ID, instance.Size)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if instance.Status == StatusShutoff {
+		err = retry(ctx, civoProvider.Log, "start instance", func() error {
+			_, err := civoProvider.Client.StartInstance(instance.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	instance, err = WaitForInstance(ctx, civoProvider.Client, instance.ID, waitOptions(civoProvider), civoProvider.Log)
+	if err != nil {
+		return err
+	}
+
+	// a concurrent create might still be running the startup script
+	return waitForStartupScript(ctx, civoProvider, instance)
 }
 
-func Stop(civoProvider *CivoProvider) error {
-	instance, err := GetDevpodInstance(civoProvider)
+// resolveDuplicateInstances returns the instance to keep if creates for the
+// machine ran concurrently, which is the one created first.
+func resolveDuplicateInstances(ctx context.Context, civoProvider *CivoProvider, created *civogo.Instance) (*civogo.Instance, error) {
+	instances, err := findMachineInstances(ctx, civoProvider)
 	if err != nil {
+		return nil, err
+	}
+
+	winner := created
+	for i := range instances {
+		if instances[i].ID == created.ID {
+			// the listed instance has the creation time
+			winner = &instances[i]
+		}
+	}
+
+	for i := range instances {
+		if createdBefore(&instances[i], winner) {
+			winner = &instances[i]
+		}
+	}
+
+	return winner, nil
+}
+
+// createdBefore orders instances by creation time and then ID, instances
+// without a creation time go last.
+func createdBefore(a, b *civogo.Instance) bool {
+	switch {
+	case a.CreatedAt.IsZero() != b.CreatedAt.IsZero():
+		return b.CreatedAt.IsZero()
+	case !a.CreatedAt.Equal(b.CreatedAt):
+		return a.CreatedAt.Before(b.CreatedAt)
+	default:
+		return a.ID < b.ID
+	}
+}
+
+func Start(ctx context.Context, civoProvider *CivoProvider) error {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if IsNotFound(err) {
+		hibernated, hibernatedErr := isHibernated(ctx, civoProvider)
+		if hibernatedErr != nil {
+			return hibernatedErr
+		} else if hibernated {
+			return resume(ctx, civoProvider)
+		}
+
+		return err
+	} else if err != nil {
 		return err
 	}
 
-	_, err = civoProvider.Client.StopInstance(instance.ID)
+	err = retry(ctx, civoProvider.Log, "start instance", func() error {
+		_, err := civoProvider.Client.StartInstance(instance.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = WaitForInstance(ctx, civoProvider.Client, instance.ID, waitOptions(civoProvider), civoProvider.Log)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func Stop(ctx context.Context, civoProvider *CivoProvider) error {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if IsNotFound(err) && civoProvider.Config.StopMode == options.StopModeHibernate {
+		// stopping a hibernated machine again
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if civoProvider.Config.StopMode == options.StopModeHibernate {
+		return hibernate(ctx, civoProvider, instance)
+	}
+
+	err = retry(ctx, civoProvider.Log, "stop instance", func() error {
+		_, err := civoProvider.Client.StopInstance(instance.ID)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -168,18 +751,67 @@ func Stop(civoProvider *CivoProvider) error {
 	return nil
 }
 
-func Status(civoProvider *CivoProvider) (client.Status, error) {
-	instance, err := GetDevpodInstance(civoProvider)
+// shutdownInstance stops the instance and waits until it is shut off.
+func shutdownInstance(ctx context.Context, civoProvider *CivoProvider, instance *civogo.Instance) error {
+	if instance.Status == StatusShutoff {
+		return nil
+	}
+
+	err := retry(ctx, civoProvider.Log, "stop instance", func() error {
+		_, err := civoProvider.Client.StopInstance(instance.ID)
+		return err
+	})
 	if err != nil {
+		return err
+	}
+
+	return waitFor(ctx, civoProvider, "instance "+instance.ID+" to shut down", func() (bool, error) {
+		instance, err := retryValue(ctx, civoProvider.Log, "get instance", func() (*civogo.Instance, error) {
+			return civoProvider.Client.GetInstance(instance.ID)
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return instance.Status == StatusShutoff, nil
+	})
+}
+
+func Status(ctx context.Context, civoProvider *CivoProvider) (client.Status, error) {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if IsNotFound(err) {
+		hibernated, err := isHibernated(ctx, civoProvider)
+		if err != nil {
+			return client.StatusNotFound, errors.Wrap(err, "get instance status")
+		} else if hibernated {
+			return client.StatusStopped, nil
+		}
+
 		return client.StatusNotFound, nil
+	} else if err != nil {
+		// an auth or network error doesn't mean the machine is gone
+		return client.StatusNotFound, errors.Wrap(err, "get instance status")
 	}
 
-	switch {
-	case instance.Status == "ACTIVE":
-		return client.StatusRunning, nil
-	case instance.Status == "SHUTOFF":
-		return client.StatusStopped, nil
-	default:
-		return client.StatusBusy, nil
+	return InstanceStatus(instance)
+}
+
+// GetInstanceDetails returns the details of the instance of the machine. Like
+// Status, a hibernated machine is stopped rather than not found.
+func GetInstanceDetails(ctx context.Context, civoProvider *CivoProvider) (*InstanceDetails, error) {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if IsNotFound(err) {
+		hibernated, err := isHibernated(ctx, civoProvider)
+		if err != nil {
+			return nil, errors.Wrap(err, "get instance status")
+		} else if hibernated {
+			return &InstanceDetails{Status: client.StatusStopped, State: StateHibernated}, nil
+		}
+
+		return NewInstanceDetails(nil), nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "get instance status")
 	}
+
+	return NewInstanceDetails(instance), nil
 }
@@ -0,0 +1,549 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeClient serializes the calls to civogo.FakeClient, so concurrent creates
+// can share it, and fills in what the fake leaves out but the provider relies
+// on: instances become active with a creation time and keep their firewall,
+// ssh keys get an ID, firewalls keep their name and reserved ips are stored.
+type fakeClient struct {
+	*civogo.FakeClient
+
+	mu      sync.Mutex
+	created int
+	ips     []civogo.IP
+
+	// barriers hold the create calls of a resource back until every
+	// concurrent create arrived, so they all create it
+	barriers map[string]*sync.WaitGroup
+}
+
+func newFakeClient(t *testing.T) *fakeClient {
+	fake, err := civogo.NewFakeClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.Quota = civogo.Quota{
+		InstanceCountLimit:   10,
+		CPUCoreLimit:         10,
+		RAMMegabytesLimit:    10240,
+		DiskGigabytesLimit:   1000,
+		PublicIPAddressLimit: 10,
+	}
+	fake.InstanceSizes = []civogo.InstanceSize{
+		{ID: "g3.small", Name: "g3.small", CPUCores: 1, RAMMegabytes: 2048, DiskGigabytes: 25, Selectable: true},
+	}
+	fake.DiskImage = []civogo.DiskImage{
+		{ID: "image-1", Name: "ubuntu-jammy", Distribution: "ubuntu", Version: "22.04"},
+	}
+
+	return &fakeClient{FakeClient: fake}
+}
+
+func newTestProvider(client civogo.Clienter, machineFolder string) *CivoProvider {
+	return &CivoProvider{
+		Config: &options.Options{
+			AgentPath:     "/var/lib/toolbox/devpod",
+			CreateTimeout: 10 * time.Second,
+			DiskImage:     "ubuntu-jammy",
+			DiskSizeGB:    20,
+			MachineFolder: machineFolder,
+			MachineID:     "devpod-test",
+			MachineType:   "g3.small",
+			PollInterval:  10 * time.Millisecond,
+			PublicIP:      true,
+			Region:        "FAKE1",
+			StopMode:      options.StopModeShutdown,
+		},
+		Client: client,
+		Log:    log.NewStreamLogger(io.Discard, io.Discard, logrus.ErrorLevel),
+	}
+}
+
+// stubSSH makes instances reachable and their startup script succeed.
+func stubSSH(t *testing.T) {
+	probe, status := probeSSH, readStartupStatus
+	probeSSH = func(address string) error { return nil }
+	readStartupStatus = func(ctx context.Context, civoProvider *CivoProvider, address string) (string, error) {
+		return "0", nil
+	}
+	t.Cleanup(func() { probeSSH, readStartupStatus = probe, status })
+}
+
+func TestCreateTwiceAdoptsInstance(t *testing.T) {
+	stubSSH(t)
+	client := newFakeClient(t)
+	civoProvider := newTestProvider(client, t.TempDir())
+
+	err := Create(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("first create: %v", err)
+	}
+
+	err = Create(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("second create: %v", err)
+	}
+
+	if len(client.Instances) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(client.Instances))
+	}
+	if len(client.SSHKeys) != 1 {
+		t.Errorf("expected 1 ssh key, got %d", len(client.SSHKeys))
+	}
+	if len(client.Firewalls) != 1 {
+		t.Errorf("expected 1 firewall, got %d", len(client.Firewalls))
+	}
+
+	state, err := LoadMachineState(civoProvider.Config.MachineFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil || state.InstanceID != client.Instances[0].ID {
+		t.Errorf("expected the machine state to record instance %s, got %+v", client.Instances[0].ID, state)
+	}
+}
+
+func TestDeleteTwiceSucceeds(t *testing.T) {
+	stubSSH(t)
+	client := newFakeClient(t)
+	civoProvider := newTestProvider(client, t.TempDir())
+
+	err := Create(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		err = Delete(context.Background(), civoProvider)
+		if err != nil {
+			t.Fatalf("delete %d: %v", i, err)
+		}
+	}
+
+	if len(client.Instances) != 0 || len(client.SSHKeys) != 0 || len(client.Firewalls) != 0 {
+		t.Errorf("expected everything to be deleted, got %d instances, %d ssh keys and %d firewalls", len(client.Instances), len(client.SSHKeys), len(client.Firewalls))
+	}
+
+	state, err := LoadMachineState(civoProvider.Config.MachineFolder)
+	if err != nil {
+		t.Fatal(err)
+	} else if state != nil {
+		t.Errorf("expected the machine state to be deleted, got %+v", state)
+	}
+}
+
+func TestConcurrentCreatesConverge(t *testing.T) {
+	stubSSH(t)
+	client := newFakeClient(t)
+	machineFolder := t.TempDir()
+
+	const creates = 2
+	client.barriers = map[string]*sync.WaitGroup{}
+	for _, resource := range []string{"ssh key", "firewall", "instance"} {
+		client.barriers[resource] = &sync.WaitGroup{}
+		client.barriers[resource].Add(creates)
+	}
+
+	errs := make(chan error, creates)
+	for i := 0; i < creates; i++ {
+		go func() {
+			errs <- Create(context.Background(), newTestProvider(client, machineFolder))
+		}()
+	}
+	for i := 0; i < creates; i++ {
+		err := <-errs
+		if err != nil {
+			t.Errorf("create: %v", err)
+		}
+	}
+
+	if client.created != creates {
+		t.Fatalf("expected %d instances to be created, got %d", creates, client.created)
+	}
+	if len(client.Instances) != 1 || len(client.Firewalls) != 1 || len(client.SSHKeys) != 1 {
+		t.Fatalf("expected the creates to converge on 1 instance, firewall and ssh key, got %d instances, %d firewalls and %d ssh keys", len(client.Instances), len(client.Firewalls), len(client.SSHKeys))
+	}
+	if client.Instances[0].FirewallID != client.Firewalls[0].ID {
+		t.Errorf("expected instance %s to use firewall %s, got %s", client.Instances[0].ID, client.Firewalls[0].ID, client.Instances[0].FirewallID)
+	}
+
+	state, err := LoadMachineState(machineFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil || state.InstanceID != client.Instances[0].ID {
+		t.Errorf("expected the machine state to record instance %s, got %+v", client.Instances[0].ID, state)
+	}
+
+	err = Delete(context.Background(), newTestProvider(client, machineFolder))
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if len(client.Instances) != 0 || len(client.Firewalls) != 0 || len(client.SSHKeys) != 0 {
+		t.Errorf("expected everything to be deleted, got %d instances, %d firewalls and %d ssh keys", len(client.Instances), len(client.Firewalls), len(client.SSHKeys))
+	}
+}
+
+func TestDeleteReleasesAllocatedReservedIP(t *testing.T) {
+	stubSSH(t)
+	client := newFakeClient(t)
+	civoProvider := newTestProvider(client, t.TempDir())
+	civoProvider.Config.ReservedIP = "true"
+	civoProvider.Config.ReleaseReservedIP = true
+
+	err := Create(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if len(client.ips) != 1 || client.ips[0].Name != "devpod-test" || client.ips[0].AssignedTo.ID != client.Instances[0].ID {
+		t.Fatalf("expected reserved ip devpod-test to be allocated and assigned, got %+v", client.ips)
+	}
+
+	err = Delete(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if len(client.ips) != 0 {
+		t.Errorf("expected the reserved ip to be released, got %+v", client.ips)
+	}
+}
+
+func TestDeleteKeepsExistingReservedIP(t *testing.T) {
+	stubSSH(t)
+	client := newFakeClient(t)
+	client.ips = []civogo.IP{{ID: "ip-1", Name: "shared", IP: "192.0.2.1"}}
+	civoProvider := newTestProvider(client, t.TempDir())
+	civoProvider.Config.ReservedIP = "shared"
+	civoProvider.Config.ReleaseReservedIP = true
+
+	err := Create(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if client.ips[0].AssignedTo.ID != client.Instances[0].ID {
+		t.Fatalf("expected the reserved ip to be assigned to %s, got %+v", client.Instances[0].ID, client.ips[0].AssignedTo)
+	}
+
+	err = Delete(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if len(client.ips) != 1 || client.ips[0].AssignedTo.ID != "" {
+		t.Errorf("expected the reserved ip to be kept and unassigned, got %+v", client.ips)
+	}
+}
+
+func TestDeleteKeepsReservedIPOfAnotherInstance(t *testing.T) {
+	stubSSH(t)
+	client := newFakeClient(t)
+	civoProvider := newTestProvider(client, t.TempDir())
+	civoProvider.Config.ReservedIP = "true"
+	civoProvider.Config.ReleaseReservedIP = true
+
+	err := Create(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// the reserved ip was moved to another instance since
+	other := civogo.AssignedTo{ID: "other-instance", Name: "other", Type: "instance"}
+	client.ips[0].AssignedTo = other
+
+	err = Delete(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if len(client.ips) != 1 || client.ips[0].AssignedTo != other {
+		t.Errorf("expected the reserved ip to stay assigned to the other instance, got %+v", client.ips)
+	}
+}
+
+func TestRollbackKeepsRecordedSize(t *testing.T) {
+	client := newFakeClient(t)
+	civoProvider := newTestProvider(client, t.TempDir())
+
+	instance, err := client.CreateInstance(&civogo.InstanceConfig{Hostname: "devpod-test", Size: "g3.medium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a resized machine whose resume failed
+	err = SaveMachineState(civoProvider.Config.MachineFolder, &MachineState{InstanceID: instance.ID, Size: "g3.medium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = deleteCreatedInstance(context.Background(), civoProvider, instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadMachineState(civoProvider.Config.MachineFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil || state.InstanceID != "" || state.Size != "g3.medium" {
+		t.Errorf("expected only the instance to be cleared from the machine state, got %+v", state)
+	}
+}
+
+func TestInstanceDetailsOfHibernatedMachine(t *testing.T) {
+	client := newFakeClient(t)
+	client.Volumes = []civogo.Volume{{ID: "volume-1", Name: "devpod-test"}}
+	civoProvider := newTestProvider(client, t.TempDir())
+	civoProvider.Config.StopMode = options.StopModeHibernate
+
+	details, err := GetInstanceDetails(context.Background(), civoProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if details.Status != "Stopped" || details.State != StateHibernated {
+		t.Errorf("expected the hibernated machine to be stopped, got %+v", details)
+	}
+}
+
+func (c *fakeClient) arrive(resource string) {
+	if barrier := c.barriers[resource]; barrier != nil {
+		barrier.Done()
+		barrier.Wait()
+	}
+}
+
+func (c *fakeClient) CreateInstance(config *civogo.InstanceConfig) (*civogo.Instance, error) {
+	c.arrive("instance")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	instance, err := c.FakeClient.CreateInstance(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.created++
+	instance.Status = StatusActive
+	instance.CreatedAt = time.Date(2024, 1, 1, 0, 0, c.created, 0, time.UTC)
+	instance.Tags = append([]string{}, config.Tags...)
+	instance.FirewallID = config.FirewallID
+	c.Instances[len(c.Instances)-1] = *instance
+	return instance, nil
+}
+
+func (c *fakeClient) NewSSHKey(name string, publicKey string) (*civogo.SimpleResponse, error) {
+	c.arrive("ssh key")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.LastID++
+	id := fmt.Sprintf("ssh-key-%d", c.LastID)
+	c.SSHKeys = append(c.SSHKeys, civogo.SSHKey{ID: id, Name: name, PublicKey: publicKey})
+	return &civogo.SimpleResponse{ID: id, Result: "success"}, nil
+}
+
+func (c *fakeClient) NewFirewall(config *civogo.FirewallConfig) (*civogo.FirewallResult, error) {
+	c.arrive("firewall")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, err := c.FakeClient.NewFirewall(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Firewalls[len(c.Firewalls)-1].Name = config.Name
+	c.Firewalls[len(c.Firewalls)-1].NetworkID = config.NetworkID
+	result.Name = config.Name
+	return result, nil
+}
+
+func (c *fakeClient) FindIP(search string) (*civogo.IP, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, ip := range c.ips {
+		if ip.ID == search || ip.Name == search || ip.IP == search {
+			return &ip, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find %s, zero matches: %w", search, civogo.ZeroMatchesError)
+}
+
+func (c *fakeClient) NewIP(request *civogo.CreateIPRequest) (*civogo.IP, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.LastID++
+	ip := civogo.IP{ID: fmt.Sprintf("ip-%d", c.LastID), Name: request.Name, IP: fmt.Sprintf("192.0.2.%d", c.LastID)}
+	c.ips = append(c.ips, ip)
+	return &ip, nil
+}
+
+func (c *fakeClient) AssignIP(id, resourceID, resourceType, region string) (*civogo.SimpleResponse, error) {
+	return c.updateIP(id, civogo.AssignedTo{ID: resourceID, Type: resourceType})
+}
+
+func (c *fakeClient) UnassignIP(id, region string) (*civogo.SimpleResponse, error) {
+	return c.updateIP(id, civogo.AssignedTo{})
+}
+
+func (c *fakeClient) updateIP(id string, assignedTo civogo.AssignedTo) (*civogo.SimpleResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.ips {
+		if c.ips[i].ID == id {
+			c.ips[i].AssignedTo = assignedTo
+			return &civogo.SimpleResponse{Result: "success"}, nil
+		}
+	}
+
+	return nil, civogo.HTTPError{Code: 404, Status: "404 Not Found", Reason: "ip " + id + " not found"}
+}
+
+func (c *fakeClient) DeleteIP(id string) (*civogo.SimpleResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.ips {
+		if c.ips[i].ID == id {
+			c.ips = append(c.ips[:i], c.ips[i+1:]...)
+			return &civogo.SimpleResponse{Result: "success"}, nil
+		}
+	}
+
+	return nil, civogo.HTTPError{Code: 404, Status: "404 Not Found", Reason: "ip " + id + " not found"}
+}
+
+// The remaining overrides only serialize the calls the provider makes and
+// copy the returned lists, which the fake modifies in place.
+
+func (c *fakeClient) GetQuota() (*civogo.Quota, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	quota := c.Quota
+	return &quota, nil
+}
+
+func (c *fakeClient) FindInstanceSizes(search string) (*civogo.InstanceSize, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.FindInstanceSizes(search)
+}
+
+func (c *fakeClient) ListDiskImages() ([]civogo.DiskImage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copied(c.FakeClient.ListDiskImages())
+}
+
+func (c *fakeClient) NewInstanceConfig() (*civogo.InstanceConfig, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.NewInstanceConfig()
+}
+
+func (c *fakeClient) ListSSHKeys() ([]civogo.SSHKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copied(c.FakeClient.ListSSHKeys())
+}
+
+func (c *fakeClient) DeleteSSHKey(id string) (*civogo.SimpleResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.DeleteSSHKey(id)
+}
+
+func (c *fakeClient) ListFirewalls() ([]civogo.Firewall, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copied(c.FakeClient.ListFirewalls())
+}
+
+func (c *fakeClient) DeleteFirewall(id string) (*civogo.SimpleResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.DeleteFirewall(id)
+}
+
+func (c *fakeClient) NewFirewallRule(rule *civogo.FirewallRuleConfig) (*civogo.FirewallRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.NewFirewallRule(rule)
+}
+
+func (c *fakeClient) ListFirewallRules(id string) ([]civogo.FirewallRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copied(c.FakeClient.ListFirewallRules(id))
+}
+
+func (c *fakeClient) ListVolumes() ([]civogo.Volume, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copied(c.FakeClient.ListVolumes())
+}
+
+func (c *fakeClient) ListInstances(page int, perPage int) (*civogo.PaginatedInstanceList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list, err := c.FakeClient.ListInstances(page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Items, _ = copied(list.Items, nil)
+	return list, nil
+}
+
+func (c *fakeClient) GetInstance(id string) (*civogo.Instance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.GetInstance(id)
+}
+
+func (c *fakeClient) UpdateInstance(instance *civogo.Instance) (*civogo.SimpleResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.UpdateInstance(instance)
+}
+
+func (c *fakeClient) DeleteInstance(id string) (*civogo.SimpleResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.FakeClient.DeleteInstance(id)
+}
+
+func copied[T any](items []T, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]T{}, items...), nil
+}
@@ -0,0 +1,257 @@
+package civo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// InstanceCost is the cost of a single instance and its data volume in the
+// current billing month.
+type InstanceCost struct {
+	ID               string    `json:"id"`
+	Machine          string    `json:"machine"`
+	Owner            string    `json:"owner"`
+	Size             string    `json:"size"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"createdAt"`
+	Hours            float64   `json:"hours"`
+	HourlyPrice      float64   `json:"hourlyPrice"`
+	PriceKnown       bool      `json:"priceKnown"`
+	PriceSource      string    `json:"priceSource,omitempty"`
+	VolumeGB         int       `json:"volumeGB,omitempty"`
+	Accrued          float64   `json:"accrued"`
+	ProjectedMonthly float64   `json:"projectedMonthly"`
+}
+
+// OwnerCost sums up the instance costs of an owner.
+type OwnerCost struct {
+	Owner            string  `json:"owner"`
+	Instances        int     `json:"instances"`
+	Accrued          float64 `json:"accrued"`
+	ProjectedMonthly float64 `json:"projectedMonthly"`
+}
+
+// CostReport is the cost of instances in the billing month from From to To.
+type CostReport struct {
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Currency  string         `json:"currency"`
+	Instances []InstanceCost `json:"instances"`
+	Owners    []OwnerCost    `json:"owners"`
+}
+
+// GetCostReport calculates the accrued and projected cost of the instances
+// for the current billing month. Billed hours come from the account charges,
+// falling back to the instance age for charges that aren't reported yet.
+func GetCostReport(ctx context.Context, client civogo.Clienter, logs log.Logger, instances []civogo.Instance, now time.Time) (*CostReport, error) {
+	now = now.UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	charges, err := retryValue(ctx, logs, "list charges", func() ([]civogo.Charge, error) {
+		return client.ListCharges(from, now)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list charges")
+	}
+
+	volumes, err := retryValue(ctx, logs, "list volumes", client.ListVolumes)
+	if err != nil {
+		return nil, errors.Wrap(err, "list volumes")
+	}
+
+	report := &CostReport{
+		From:      from,
+		To:        to,
+		Currency:  "USD",
+		Instances: []InstanceCost{},
+	}
+	for _, instance := range instances {
+		report.Instances = append(report.Instances, instanceCost(instance, charges, volumes, from, to, now))
+	}
+
+	sort.Slice(report.Instances, func(i, j int) bool {
+		return report.Instances[i].Machine < report.Instances[j].Machine
+	})
+	report.Owners = ownerCosts(report.Instances)
+
+	return report, nil
+}
+
+func instanceCost(instance civogo.Instance, charges []civogo.Charge, volumes []civogo.Volume, from, to, now time.Time) InstanceCost {
+	machineID := TagValue(instance.Tags, MachineTagPrefix)
+	if machineID == "" {
+		machineID = instance.Hostname
+	}
+
+	cost := InstanceCost{
+		ID:        instance.ID,
+		Machine:   machineID,
+		Owner:     TagValue(instance.Tags, OwnerTagPrefix),
+		Size:      instance.Size,
+		Status:    instance.Status,
+		CreatedAt: instance.CreatedAt,
+	}
+	cost.HourlyPrice, cost.PriceSource = HourlyPrice(instance.Size)
+	cost.PriceKnown = cost.PriceSource != ""
+
+	instanceHours, volumeHours := chargedHours(charges, instance)
+	if instanceHours == 0 {
+		instanceHours = hoursSince(instance.CreatedAt, from, now)
+	}
+	cost.Hours = instanceHours
+
+	// civo bills stopped instances as well, so every instance costs the
+	// full price until it is deleted
+	remainingHours := to.Sub(now).Hours()
+	cost.Accrued = instanceHours * cost.HourlyPrice
+	cost.ProjectedMonthly = cost.Accrued + remainingHours*cost.HourlyPrice
+
+	for _, volume := range volumes {
+		if volume.Name != machineID {
+			continue
+		}
+
+		hours := volumeHours
+		if hours == 0 {
+			hours = hoursSince(volume.CreatedAt, from, now)
+		}
+
+		volumeHourlyPrice := float64(volume.SizeGigabytes) * volumePricePerGBMonth / hoursPerMonth
+		cost.VolumeGB = volume.SizeGigabytes
+		cost.Accrued += hours * volumeHourlyPrice
+		cost.ProjectedMonthly += hours*volumeHourlyPrice + remainingHours*volumeHourlyPrice
+	}
+
+	return cost
+}
+
+// chargedHours returns the billed hours of the instance and of its volume.
+// Charges only reference the resource by label, which is its name or ID.
+func chargedHours(charges []civogo.Charge, instance civogo.Instance) (float64, float64) {
+	instanceHours, volumeHours := 0, 0
+	for _, charge := range charges {
+		if charge.Label != instance.Hostname && !strings.Contains(charge.Label, instance.ID) {
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(charge.Code), "volume") {
+			volumeHours += charge.NumHours
+		} else {
+			instanceHours += charge.NumHours
+		}
+	}
+
+	return float64(instanceHours), float64(volumeHours)
+}
+
+// hoursSince returns the hours from createdAt, or the start of the billing
+// month if earlier, until now.
+func hoursSince(createdAt, from, now time.Time) float64 {
+	if createdAt.Before(from) {
+		createdAt = from
+	}
+
+	if createdAt.After(now) {
+		return 0
+	}
+
+	return now.Sub(createdAt).Hours()
+}
+
+func ownerCosts(instances []InstanceCost) []OwnerCost {
+	byOwner := map[string]*OwnerCost{}
+	for _, instance := range instances {
+		owner := instance.Owner
+		if owner == "" {
+			owner = "unknown"
+		}
+
+		ownerCost, ok := byOwner[owner]
+		if !ok {
+			ownerCost = &OwnerCost{Owner: owner}
+			byOwner[owner] = ownerCost
+		}
+
+		ownerCost.Instances++
+		ownerCost.Accrued += instance.Accrued
+		ownerCost.ProjectedMonthly += instance.ProjectedMonthly
+	}
+
+	owners := []OwnerCost{}
+	for _, ownerCost := range byOwner {
+		owners = append(owners, *ownerCost)
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i].Owner < owners[j].Owner
+	})
+
+	return owners
+}
+
+// PrintJSON writes the report as indented json.
+func (r *CostReport) PrintJSON(out io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// Print writes the instance costs as a table, followed by the totals per
+// owner if byOwner is set.
+func (r *CostReport) Print(out io.Writer, byOwner bool) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Billing period %s - %s (%s)\n\n", r.From.Format("2006-01-02"), r.To.Format("2006-01-02"), r.Currency)
+	fmt.Fprintln(w, "MACHINE\tOWNER\tSIZE\tSTATUS\tVOLUME\tHOURS\tACCRUED\tPROJECTED (MONTH)")
+	sources := map[string]bool{}
+	for _, instance := range r.Instances {
+		volume := "-"
+		if instance.VolumeGB > 0 {
+			volume = fmt.Sprintf("%dGB", instance.VolumeGB)
+		}
+
+		accrued, projected := formatPrice(instance.Accrued), formatPrice(instance.ProjectedMonthly)
+		sources[instance.PriceSource] = true
+		if instance.PriceSource == "" {
+			accrued += " (size price unknown)"
+		} else if instance.PriceSource == PriceSourceTable {
+			accrued += "*"
+			projected += "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%.1f\t%s\t%s\n", instance.Machine, instance.Owner, instance.Size, instance.Status, volume, instance.Hours, accrued, projected)
+	}
+
+	if byOwner {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "OWNER\tINSTANCES\tACCRUED\tPROJECTED (MONTH)")
+		for _, owner := range r.Owners {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", owner.Owner, owner.Instances, formatPrice(owner.Accrued), formatPrice(owner.ProjectedMonthly))
+		}
+	}
+
+	if note := priceNote(sources); note != "" {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, note)
+	}
+
+	return w.Flush()
+}
+
+func formatPrice(price float64) string {
+	return fmt.Sprintf("$%.2f", price)
+}
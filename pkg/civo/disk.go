@@ -0,0 +1,288 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// ResolveDiskImage finds the disk image for the given search term, which can be
+// an image ID, an image name (e.g. ubuntu-jammy) or a distribution (e.g. debian).
+func ResolveDiskImage(ctx context.Context, client civogo.Clienter, logs log.Logger, search, region string) (*civogo.DiskImage, error) {
+	images, err := retryValue(ctx, logs, "list disk images", client.ListDiskImages)
+	if err != nil {
+		return nil, errors.Wrap(err, "list disk images")
+	}
+
+	for _, image := range images {
+		if image.ID == search || strings.EqualFold(image.Name, search) {
+			return &image, nil
+		}
+	}
+
+	matches := []civogo.DiskImage{}
+	for _, image := range images {
+		if strings.EqualFold(image.Distribution, search) {
+			matches = append(matches, image)
+		}
+	}
+
+	if len(matches) == 1 {
+		return &matches[0], nil
+	} else if len(matches) > 1 {
+		return nil, fmt.Errorf(
+			"disk image %q is ambiguous in region %s, please use one of: %s",
+			search,
+			region,
+			formatDiskImages(matches),
+		)
+	}
+
+	names := []string{}
+	for _, image := range images {
+		names = append(names, image.Name)
+	}
+
+	message := fmt.Sprintf("disk image %q not found in region %s", search, region)
+	if suggestion := didYouMean(search, names); suggestion != "" {
+		message += fmt.Sprintf(", did you mean %q?", suggestion)
+	} else {
+		message += ","
+	}
+
+	return nil, fmt.Errorf("%s available images: %s", message, formatDiskImages(images))
+}
+
+func formatDiskImages(images []civogo.DiskImage) string {
+	names := []string{}
+	for _, image := range images {
+		names = append(names, fmt.Sprintf("%s (%s)", image.Name, image.ID))
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// extraDiskSizeGB returns how much storage has to be added on top of the root
+// disk of the instance size to satisfy the requested disk size.
+func extraDiskSizeGB(ctx context.Context, civoProvider *CivoProvider) (int, error) {
+	sizeName, err := machineSize(civoProvider)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := retryValue(ctx, civoProvider.Log, "find instance size", func() (*civogo.InstanceSize, error) {
+		return civoProvider.Client.FindInstanceSizes(sizeName)
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "find instance size %s", sizeName)
+	}
+
+	if civoProvider.Config.DiskSizeGB <= size.DiskGigabytes {
+		return 0, nil
+	}
+
+	return civoProvider.Config.DiskSizeGB - size.DiskGigabytes, nil
+}
+
+func GetDevpodVolume(ctx context.Context, civoProvider *CivoProvider) (*civogo.Volume, error) {
+	volumes, err := retryValue(ctx, civoProvider.Log, "list volumes", civoProvider.Client.ListVolumes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, volume := range volumes {
+		if volume.Name == civoProvider.Config.MachineID {
+			return &volume, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// dataVolumeSizeGB returns the size of the data volume for the machine, which
+// is either the persistent CIVO_VOLUME_SIZE or the storage missing on the root
+// disk. Zero means no data volume is needed.
+func dataVolumeSizeGB(ctx context.Context, civoProvider *CivoProvider) (int, error) {
+	if civoProvider.Config.VolumeSizeGB > 0 {
+		return civoProvider.Config.VolumeSizeGB, nil
+	}
+
+	return extraDiskSizeGB(ctx, civoProvider)
+}
+
+// attachDataVolume attaches the data volume of the machine to the instance,
+// reusing the volume of a previous instance with the same machine ID.
+func attachDataVolume(ctx context.Context, civoProvider *CivoProvider, instanceID, networkID string, sizeGB int, journal *createJournal) error {
+	volume, err := GetDevpodVolume(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	volumeID := ""
+	if volume != nil {
+		if volume.InstanceID != "" && volume.InstanceID != instanceID {
+			return fmt.Errorf("volume %s is still attached to instance %s", volume.Name, volume.InstanceID)
+		}
+
+		if volume.SizeGigabytes < sizeGB {
+			civoProvider.Log.Warnf("Reusing volume %s with %dGB, which is smaller than the requested %dGB", volume.Name, volume.SizeGigabytes, sizeGB)
+		} else {
+			civoProvider.Log.Infof("Reusing volume %s", volume.Name)
+		}
+
+		volumeID = volume.ID
+		journal.record("volume attachment "+volume.Name, func(ctx context.Context) error {
+			return detachDataVolume(ctx, civoProvider)
+		})
+	} else {
+		volumeID, err = createDataVolume(ctx, civoProvider, networkID, sizeGB)
+		if err != nil {
+			return err
+		}
+
+		createdID := volumeID
+		journal.record("volume "+civoProvider.Config.MachineID, func(ctx context.Context) error {
+			return deleteCreatedVolume(ctx, civoProvider, createdID)
+		})
+	}
+
+	err = retry(ctx, civoProvider.Log, "attach volume", func() error {
+		_, err := civoProvider.Client.AttachVolume(volumeID, instanceID)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "attach volume")
+	}
+
+	return nil
+}
+
+func createDataVolume(ctx context.Context, civoProvider *CivoProvider, networkID string, sizeGB int) (string, error) {
+	volumeID, err := createOnce(ctx, civoProvider.Log, "volume",
+		func() (string, error) {
+			result, err := civoProvider.Client.NewVolume(&civogo.VolumeConfig{
+				Name:          civoProvider.Config.MachineID,
+				NetworkID:     networkID,
+				Region:        civoProvider.Config.Region,
+				SizeGigabytes: sizeGB,
+			})
+			if err != nil {
+				return "", err
+			}
+
+			return result.ID, nil
+		},
+		func() (string, bool, error) {
+			volume, err := GetDevpodVolume(ctx, civoProvider)
+			if err != nil || volume == nil {
+				return "", false, err
+			}
+
+			return volume.ID, true, nil
+		},
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "create volume")
+	}
+
+	return volumeID, nil
+}
+
+// detachDataVolume detaches the data volume so it survives the instance.
+func detachDataVolume(ctx context.Context, civoProvider *CivoProvider) error {
+	volume, err := GetDevpodVolume(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	if volume == nil || volume.InstanceID == "" {
+		return nil
+	}
+
+	return detachVolume(ctx, civoProvider, volume)
+}
+
+// detachVolume detaches the volume and waits until it is detached.
+func detachVolume(ctx context.Context, civoProvider *CivoProvider, volume *civogo.Volume) error {
+	err := retry(ctx, civoProvider.Log, "detach volume", func() error {
+		_, err := civoProvider.Client.DetachVolume(volume.ID)
+		return err
+	})
+	if IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "detach volume")
+	}
+
+	return waitFor(ctx, civoProvider, "volume "+volume.Name+" to be detached", func() (bool, error) {
+		volume, err := GetDevpodVolume(ctx, civoProvider)
+		if err != nil {
+			return false, err
+		}
+
+		return volume == nil || volume.InstanceID == "", nil
+	})
+}
+
+// keepDataVolume returns if the data volume should survive deleting the machine.
+func keepDataVolume(civoProvider *CivoProvider) bool {
+	return civoProvider.Config.VolumeSizeGB > 0 && !civoProvider.Config.DeleteVolume
+}
+
+func deleteDataVolume(ctx context.Context, civoProvider *CivoProvider) error {
+	volume, err := GetDevpodVolume(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	if volume == nil {
+		return nil
+	}
+
+	return removeVolume(ctx, civoProvider, volume)
+}
+
+// deleteCreatedVolume deletes the volume created by a failed create, unless it
+// was replaced by a concurrent create in the meantime.
+func deleteCreatedVolume(ctx context.Context, civoProvider *CivoProvider, volumeID string) error {
+	volume, err := GetDevpodVolume(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	if volume == nil || volume.ID != volumeID {
+		return nil
+	}
+
+	return removeVolume(ctx, civoProvider, volume)
+}
+
+func removeVolume(ctx context.Context, civoProvider *CivoProvider, volume *civogo.Volume) error {
+	if volume.InstanceID != "" {
+		err := detachVolume(ctx, civoProvider, volume)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := retry(ctx, civoProvider.Log, "delete volume", func() error {
+		_, err := civoProvider.Client.DeleteVolume(volume.ID)
+		return err
+	})
+	if IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "delete volume")
+	}
+
+	return waitFor(ctx, civoProvider, "volume "+volume.Name+" to be deleted", func() (bool, error) {
+		volume, err := GetDevpodVolume(ctx, civoProvider)
+		return volume == nil, err
+	})
+}
@@ -0,0 +1,288 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/pkg/errors"
+)
+
+// EgressCIDR can be used in CIVO_ALLOWED_CIDRS to allow the public IP of the
+// machine running the provider.
+const EgressCIDR = "egress"
+
+const egressIPURL = "https://api.ipify.org"
+
+// ensureFirewall returns the firewall to launch the instance into. It's either
+// the existing CIVO_FIREWALL or a new one for the machine that only allows ssh.
+func ensureFirewall(ctx context.Context, civoProvider *CivoProvider, networkID string, journal *createJournal) (string, error) {
+	if civoProvider.Config.Firewall != "" {
+		firewall, err := retryValue(ctx, civoProvider.Log, "find firewall", func() (*civogo.Firewall, error) {
+			return findFirewall(civoProvider.Client, civoProvider.Config.Firewall)
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "find firewall %s", civoProvider.Config.Firewall)
+		}
+
+		if firewall.NetworkID != "" && firewall.NetworkID != networkID {
+			return "", fmt.Errorf("firewall %s belongs to network %s, but the instance is created in network %s", firewall.Name, firewall.NetworkID, networkID)
+		}
+
+		return firewall.ID, nil
+	}
+
+	firewall, err := GetDevpodFirewall(ctx, civoProvider)
+	if err != nil {
+		return "", err
+	} else if firewall != nil {
+		return firewall.ID, nil
+	}
+
+	cidrs, err := resolveAllowedCIDRs(ctx, civoProvider.Config.AllowedCIDRs)
+	if err != nil {
+		return "", err
+	}
+
+	createRules := false
+	firewallID, err := createOnce(ctx, civoProvider.Log, "firewall",
+		func() (string, error) {
+			result, err := civoProvider.Client.NewFirewall(&civogo.FirewallConfig{
+				Name:        civoProvider.Config.MachineID,
+				Region:      civoProvider.Config.Region,
+				NetworkID:   networkID,
+				CreateRules: &createRules,
+			})
+			if err != nil {
+				return "", err
+			}
+
+			return result.ID, nil
+		},
+		func() (string, bool, error) {
+			firewall, err := GetDevpodFirewall(ctx, civoProvider)
+			if err != nil || firewall == nil {
+				return "", false, err
+			}
+
+			return firewall.ID, true, nil
+		},
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "create firewall")
+	}
+
+	journal.record("firewall "+civoProvider.Config.MachineID, func(ctx context.Context) error {
+		return deleteCreatedFirewall(ctx, civoProvider, firewallID)
+	})
+
+	rules := []*civogo.FirewallRuleConfig{
+		{
+			FirewallID: firewallID,
+			Protocol:   "tcp",
+			StartPort:  sshPort,
+			EndPort:    sshPort,
+			Cidr:       cidrs,
+			Direction:  "ingress",
+			Action:     "allow",
+			Label:      "devpod ssh",
+		},
+	}
+	for _, protocol := range []string{"tcp", "udp", "icmp"} {
+		rules = append(rules, &civogo.FirewallRuleConfig{
+			FirewallID: firewallID,
+			Protocol:   protocol,
+			StartPort:  "1",
+			EndPort:    "65535",
+			Cidr:       []string{"0.0.0.0/0"},
+			Direction:  "egress",
+			Action:     "allow",
+			Label:      "devpod egress " + protocol,
+		})
+	}
+
+	for _, rule := range rules {
+		err = createFirewallRule(ctx, civoProvider, rule)
+		if err != nil {
+			return "", errors.Wrapf(err, "create firewall rule %s", rule.Label)
+		}
+	}
+
+	return firewallID, nil
+}
+
+func createFirewallRule(ctx context.Context, civoProvider *CivoProvider, rule *civogo.FirewallRuleConfig) error {
+	_, err := createOnce(ctx, civoProvider.Log, "firewall rule "+rule.Label,
+		func() (*civogo.FirewallRule, error) {
+			return civoProvider.Client.NewFirewallRule(rule)
+		},
+		func() (*civogo.FirewallRule, bool, error) {
+			rules, err := civoProvider.Client.ListFirewallRules(rule.FirewallID)
+			if err != nil {
+				return nil, false, err
+			}
+
+			for _, r := range rules {
+				if r.Label == rule.Label {
+					return &r, true, nil
+				}
+			}
+
+			return nil, false, nil
+		},
+	)
+
+	return err
+}
+
+// findFirewall returns the firewall with the exact name or ID.
+func findFirewall(client civogo.Clienter, search string) (*civogo.Firewall, error) {
+	firewall, err := client.FindFirewall(search)
+	if errors.Is(err, civogo.MultipleMatchesError) {
+		return nil, errors.Wrapf(civogo.ZeroMatchesError, "no exact match for %s", search)
+	} else if err != nil {
+		return nil, err
+	}
+
+	// FindFirewall also returns partial matches
+	if firewall.Name != search && firewall.ID != search {
+		return nil, errors.Wrapf(civogo.ZeroMatchesError, "no exact match for %s", search)
+	}
+
+	return firewall, nil
+}
+
+// GetDevpodFirewall returns the firewall created for the machine, if any. A
+// reused CIVO_FIREWALL is never returned, so it isn't deleted with the machine.
+func GetDevpodFirewall(ctx context.Context, civoProvider *CivoProvider) (*civogo.Firewall, error) {
+	firewalls, err := listDevpodFirewalls(ctx, civoProvider)
+	if err != nil || len(firewalls) == 0 {
+		return nil, err
+	}
+
+	return &firewalls[0], nil
+}
+
+// listDevpodFirewalls returns the firewalls created for the machine, which are
+// more than one only if creates ran concurrently.
+func listDevpodFirewalls(ctx context.Context, civoProvider *CivoProvider) ([]civogo.Firewall, error) {
+	firewalls, err := retryValue(ctx, civoProvider.Log, "list firewalls", civoProvider.Client.ListFirewalls)
+	if err != nil {
+		return nil, err
+	}
+
+	machineFirewalls := []civogo.Firewall{}
+	for _, firewall := range firewalls {
+		if firewall.Name == civoProvider.Config.MachineID {
+			machineFirewalls = append(machineFirewalls, firewall)
+		}
+	}
+
+	return machineFirewalls, nil
+}
+
+// deleteFirewall deletes the firewalls created for the machine, if any.
+func deleteFirewall(ctx context.Context, civoProvider *CivoProvider) error {
+	firewalls, err := listDevpodFirewalls(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	for _, firewall := range firewalls {
+		err = removeFirewall(ctx, civoProvider, firewall.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteCreatedFirewall deletes the firewall created by a failed or duplicate
+// create by its ID, unless a concurrent create found it and launched its
+// instance into it.
+func deleteCreatedFirewall(ctx context.Context, civoProvider *CivoProvider, firewallID string) error {
+	instances, err := listAllInstances(ctx, civoProvider.Client, civoProvider.Log)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		if instance.FirewallID == firewallID {
+			civoProvider.Log.Debugf("Keeping firewall %s, instance %s uses it", firewallID, instance.ID)
+			return nil
+		}
+	}
+
+	return removeFirewall(ctx, civoProvider, firewallID)
+}
+
+func removeFirewall(ctx context.Context, civoProvider *CivoProvider, firewallID string) error {
+	err := retry(ctx, civoProvider.Log, "delete firewall", func() error {
+		_, err := civoProvider.Client.DeleteFirewall(firewallID)
+		return err
+	})
+	if err != nil && !IsNotFound(err) {
+		return errors.Wrap(err, "delete firewall")
+	}
+
+	return nil
+}
+
+func resolveAllowedCIDRs(ctx context.Context, allowedCIDRs []string) ([]string, error) {
+	if len(allowedCIDRs) == 0 {
+		return []string{"0.0.0.0/0"}, nil
+	}
+
+	cidrs := []string{}
+	for _, cidr := range allowedCIDRs {
+		if cidr != EgressCIDR {
+			cidrs = append(cidrs, cidr)
+			continue
+		}
+
+		ip, err := egressIP(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "determine egress ip")
+		}
+
+		cidrs = append(cidrs, ip+"/32")
+	}
+
+	return cidrs, nil
+}
+
+func egressIP(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, egressIPURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s from %s", resp.Status, egressIPURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil || ip.To4() == nil {
+		return "", fmt.Errorf("unexpected egress ip %q", strings.TrimSpace(string(body)))
+	}
+
+	return ip.String(), nil
+}
@@ -0,0 +1,412 @@
+package civo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+	"github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/loft-sh/devpod/pkg/provider"
+	"github.com/pkg/errors"
+)
+
+// The kinds of resources the provider creates for a machine, in the order
+// they are deleted.
+const (
+	KindInstance   = "instance"
+	KindVolume     = "volume"
+	KindReservedIP = "reserved ip"
+	KindFirewall   = "firewall"
+	KindSSHKey     = "ssh key"
+)
+
+const (
+	gcWaitTimeout  = 10 * time.Minute
+	gcPollInterval = 5 * time.Second
+)
+
+// GCOptions configure which orphaned resources are collected.
+type GCOptions struct {
+	// KnownMachines are the names of the machines that still exist locally,
+	// their resources are never collected
+	KnownMachines map[string]bool
+
+	// Owner only collects resources of machines with this owner, unless
+	// AllOwners is set
+	Owner     string
+	AllOwners bool
+
+	// MinAge skips machines that were created more recently
+	MinAge time.Duration
+
+	DryRun bool
+}
+
+// GCResource is a resource of a machine that doesn't exist locally anymore.
+type GCResource struct {
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Machine   string     `json:"machine"`
+	Owner     string     `json:"owner,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	Reason    string     `json:"reason"`
+	Deleted   bool       `json:"deleted"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// GCReport lists the orphaned resources and the ones that were skipped.
+type GCReport struct {
+	DryRun   bool         `json:"dryRun"`
+	Orphaned []GCResource `json:"orphaned"`
+	Skipped  []GCResource `json:"skipped"`
+}
+
+type gcMachine struct {
+	name      string
+	owner     string
+	createdAt time.Time
+	resources []GCResource
+}
+
+// LocalMachines returns the resource names of the machines in all DevPod
+// contexts of the local user.
+func LocalMachines() (map[string]bool, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	devpodContexts, err := os.ReadDir(filepath.Join(configDir, "contexts"))
+	if os.IsNotExist(err) {
+		return known, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "list devpod contexts")
+	}
+
+	for _, devpodContext := range devpodContexts {
+		if !devpodContext.IsDir() {
+			continue
+		}
+
+		machinesDir, err := provider.GetMachinesDir(devpodContext.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		machines, err := os.ReadDir(machinesDir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "list machines of context %s", devpodContext.Name())
+		}
+
+		for _, machine := range machines {
+			if !machine.IsDir() {
+				continue
+			}
+
+			// keep machines with a broken config as well
+			machineID := machine.Name()
+			machineConfig, err := provider.LoadMachineConfig(devpodContext.Name(), machine.Name())
+			if err == nil && machineConfig.ID != "" {
+				machineID = machineConfig.ID
+			}
+
+			known[options.MachineIDPrefix+machineID] = true
+		}
+	}
+
+	return known, nil
+}
+
+// CollectGarbage finds the resources of machines that don't exist locally
+// anymore and deletes them, unless DryRun is set. Resources are grouped by
+// machine, so a machine is skipped or collected as a whole.
+func CollectGarbage(ctx context.Context, client *civogo.Client, logs log.Logger, gcOptions GCOptions) (*GCReport, error) {
+	machines, err := listMachineResources(ctx, client, logs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &GCReport{
+		DryRun:   gcOptions.DryRun,
+		Orphaned: []GCResource{},
+		Skipped:  []GCResource{},
+	}
+	failed := 0
+	for _, machine := range machines {
+		if gcOptions.KnownMachines[machine.name] {
+			continue
+		}
+
+		reason := gcSkipReason(machine, gcOptions)
+		if reason != "" {
+			for _, resource := range machine.resources {
+				resource.Reason = reason
+				report.Skipped = append(report.Skipped, resource)
+			}
+			continue
+		}
+
+		var machineProvider *CivoProvider
+		if !gcOptions.DryRun {
+			logs.Infof("Deleting the resources of machine %s", machine.name)
+			machineProvider = gcProvider(client, logs, machine.name)
+		}
+
+		for _, resource := range machine.resources {
+			resource.Reason = "no local machine " + strings.TrimPrefix(machine.name, options.MachineIDPrefix)
+			if machineProvider != nil {
+				err = deleteMachineResource(ctx, machineProvider, resource)
+				if err != nil {
+					logs.Warnf("Error deleting %s %s: %v", resource.Kind, resource.Name, err)
+					resource.Error = err.Error()
+					failed++
+				} else {
+					resource.Deleted = true
+				}
+			}
+
+			report.Orphaned = append(report.Orphaned, resource)
+		}
+	}
+
+	if failed > 0 {
+		return report, fmt.Errorf("deleting %d orphaned resources failed", failed)
+	}
+
+	return report, nil
+}
+
+// listMachineResources groups the resources named after DevPod machines by
+// machine. Pool instances and their resources are left to the pool.
+func listMachineResources(ctx context.Context, client *civogo.Client, logs log.Logger) ([]*gcMachine, error) {
+	instances, err := listAllInstances(ctx, client, logs)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := retryValue(ctx, logs, "list volumes", client.ListVolumes)
+	if err != nil {
+		return nil, errors.Wrap(err, "list volumes")
+	}
+
+	ips, err := retryValue(ctx, logs, "list reserved ips", client.ListIPs)
+	if err != nil {
+		return nil, errors.Wrap(err, "list reserved ips")
+	}
+
+	firewalls, err := retryValue(ctx, logs, "list firewalls", client.ListFirewalls)
+	if err != nil {
+		return nil, errors.Wrap(err, "list firewalls")
+	}
+
+	sshKeys, err := retryValue(ctx, logs, "list ssh keys", client.ListSSHKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "list ssh keys")
+	}
+
+	byName := map[string]*gcMachine{}
+	add := func(machineName string, resource GCResource, createdAt time.Time) {
+		machine, ok := byName[machineName]
+		if !ok {
+			machine = &gcMachine{name: machineName}
+			byName[machineName] = machine
+		}
+
+		if !createdAt.IsZero() {
+			resource.CreatedAt = &createdAt
+			if machine.createdAt.IsZero() || createdAt.Before(machine.createdAt) {
+				machine.createdAt = createdAt
+			}
+		}
+
+		resource.Machine = machineName
+		machine.resources = append(machine.resources, resource)
+	}
+
+	for _, instance := range instances {
+		if hasTag(instance.Tags, PoolTag) || (!IsDevpodInstance(&instance) && !isMachineResourceName(instance.Hostname)) {
+			continue
+		}
+
+		machineName := TagValue(instance.Tags, MachineTagPrefix)
+		if machineName == "" {
+			machineName = instance.Hostname
+		}
+
+		add(machineName, GCResource{Kind: KindInstance, ID: instance.ID, Name: instance.Hostname}, instance.CreatedAt)
+		if owner := TagValue(instance.Tags, OwnerTagPrefix); owner != "" {
+			byName[machineName].owner = owner
+		}
+	}
+
+	for _, volume := range volumes {
+		if isMachineResourceName(volume.Name) {
+			add(volume.Name, GCResource{Kind: KindVolume, ID: volume.ID, Name: volume.Name}, volume.CreatedAt)
+		}
+	}
+
+	for _, ip := range ips.Items {
+		if isMachineResourceName(ip.Name) {
+			add(ip.Name, GCResource{Kind: KindReservedIP, ID: ip.ID, Name: ip.Name}, time.Time{})
+		}
+	}
+
+	for _, firewall := range firewalls {
+		if isMachineResourceName(firewall.Name) {
+			add(firewall.Name, GCResource{Kind: KindFirewall, ID: firewall.ID, Name: firewall.Name}, time.Time{})
+		}
+	}
+
+	for _, sshKey := range sshKeys {
+		if isMachineResourceName(sshKey.Name) {
+			add(sshKey.Name, GCResource{Kind: KindSSHKey, ID: sshKey.ID, Name: sshKey.Name}, sshKey.CreatedAt)
+		}
+	}
+
+	machines := []*gcMachine{}
+	for _, machine := range byName {
+		for i := range machine.resources {
+			machine.resources[i].Owner = machine.owner
+		}
+
+		machines = append(machines, machine)
+	}
+
+	sort.Slice(machines, func(i, j int) bool {
+		return machines[i].name < machines[j].name
+	})
+
+	return machines, nil
+}
+
+// gcSkipReason returns why the resources of the machine are kept, or an empty
+// string if they are collected.
+func gcSkipReason(machine *gcMachine, gcOptions GCOptions) string {
+	if !gcOptions.AllOwners {
+		// resources without an instance don't have an owner, they might belong
+		// to a hibernated machine or a create in progress of someone else
+		if machine.owner == "" {
+			return "owner unknown"
+		} else if machine.owner != sanitizeTag(gcOptions.Owner) {
+			return "owned by " + machine.owner
+		}
+	}
+
+	if !machine.createdAt.IsZero() && time.Since(machine.createdAt) < gcOptions.MinAge {
+		return fmt.Sprintf("created %s ago", formatAge(time.Since(machine.createdAt)))
+	}
+
+	return ""
+}
+
+func isMachineResourceName(name string) bool {
+	return strings.HasPrefix(name, options.MachineIDPrefix) && !strings.HasPrefix(name, PoolTag+"-")
+}
+
+// gcProvider returns a provider for deleting the resources of the machine with
+// the existing helpers, which look them up by the machine name.
+func gcProvider(client *civogo.Client, logs log.Logger, machineName string) *CivoProvider {
+	return &CivoProvider{
+		Config: &options.Options{
+			MachineID:     machineName,
+			Region:        client.Region,
+			ReservedIP:    "true",
+			PublicIP:      true,
+			CreateTimeout: gcWaitTimeout,
+			PollInterval:  gcPollInterval,
+		},
+		Client: client,
+		Log:    logs,
+	}
+}
+
+func deleteMachineResource(ctx context.Context, machineProvider *CivoProvider, resource GCResource) error {
+	switch resource.Kind {
+	case KindInstance:
+		return deleteInstance(ctx, machineProvider, resource.ID)
+	case KindVolume:
+		return deleteDataVolume(ctx, machineProvider)
+	case KindReservedIP:
+		return deleteOrphanedReservedIP(ctx, machineProvider)
+	case KindFirewall:
+		return deleteFirewall(ctx, machineProvider)
+	case KindSSHKey:
+		return deleteSSHKey(ctx, machineProvider)
+	default:
+		return fmt.Errorf("unknown resource kind %s", resource.Kind)
+	}
+}
+
+// PrintJSON writes the report as indented json.
+func (r *GCReport) PrintJSON(out io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// Print writes the orphaned and skipped resources as tables.
+func (r *GCReport) Print(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if len(r.Orphaned) == 0 {
+		fmt.Fprintln(w, "No orphaned resources found")
+	} else {
+		fmt.Fprintln(w, "KIND\tNAME\tOWNER\tAGE\tRESULT")
+		for _, resource := range r.Orphaned {
+			result := "deleted"
+			if r.DryRun {
+				result = "would be deleted"
+			} else if resource.Error != "" {
+				result = "failed: " + resource.Error
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", resource.Kind, resource.Name, resource.Owner, resourceAge(resource), result)
+		}
+	}
+
+	if len(r.Skipped) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "SKIPPED\tNAME\tOWNER\tAGE\tREASON")
+		for _, resource := range r.Skipped {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", resource.Kind, resource.Name, resource.Owner, resourceAge(resource), resource.Reason)
+		}
+	}
+
+	return w.Flush()
+}
+
+func resourceAge(resource GCResource) string {
+	if resource.CreatedAt == nil {
+		return "-"
+	}
+
+	return formatAge(time.Since(*resource.CreatedAt))
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
@@ -0,0 +1,115 @@
+package civo
+
+import (
+	"context"
+	"net"
+	"os/exec"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+	"github.com/pkg/errors"
+)
+
+// isHibernated returns if the machine is hibernated, which is when there's no
+// instance but the data volume is left.
+func isHibernated(ctx context.Context, civoProvider *CivoProvider) (bool, error) {
+	if civoProvider.Config.StopMode != options.StopModeHibernate {
+		return false, nil
+	}
+
+	volume, err := GetDevpodVolume(ctx, civoProvider)
+	if err != nil {
+		return false, err
+	}
+
+	return volume != nil, nil
+}
+
+// hibernate deletes the instance, so it isn't billed while stopped. The data
+// volume with the workspace, the reserved IP, firewall and ssh key are kept to
+// recreate it on start.
+func hibernate(ctx context.Context, civoProvider *CivoProvider, instance *civogo.Instance) error {
+	if isLocalInstance(instance) {
+		return hibernateSelf(ctx, civoProvider, instance)
+	}
+
+	civoProvider.Log.Infof("Hibernating instance %s", instance.ID)
+
+	// shut down cleanly before detaching the volume
+	err := shutdownInstance(ctx, civoProvider, instance)
+	if err != nil {
+		return err
+	}
+
+	err = detachDataVolume(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	err = unassignReservedIP(ctx, civoProvider, instance.ID, false)
+	if err != nil {
+		return err
+	}
+
+	err = deleteInstance(ctx, civoProvider, instance.ID)
+	if err != nil {
+		return err
+	}
+
+	// keep the recorded size, so a resized machine is recreated with it
+	return updateMachineState(civoProvider.Config.MachineFolder, func(state *MachineState) {
+		state.InstanceID = ""
+	})
+}
+
+// hibernateSelf hibernates the instance the provider runs on, which happens
+// when the agent stops it after the inactivity timeout. It can't wait for its
+// own shutdown, so it flushes the disks and deletes itself, civo detaches the
+// volume and reserved IP from deleted instances.
+func hibernateSelf(ctx context.Context, civoProvider *CivoProvider, instance *civogo.Instance) error {
+	civoProvider.Log.Infof("Hibernating instance %s from within", instance.ID)
+
+	err := exec.CommandContext(ctx, "sync").Run()
+	if err != nil {
+		civoProvider.Log.Warnf("Error flushing disks: %v", err)
+	}
+
+	err = retry(ctx, civoProvider.Log, "delete instance", func() error {
+		_, err := civoProvider.Client.DeleteInstance(instance.ID)
+		return err
+	})
+	if err != nil && !IsNotFound(err) {
+		return errors.Wrapf(err, "delete instance %s", instance.ID)
+	}
+
+	return nil
+}
+
+// resume recreates the instance of a hibernated machine from the current
+// options and reattaches its data volume and reserved IP.
+func resume(ctx context.Context, civoProvider *CivoProvider) error {
+	civoProvider.Log.Infof("Resuming hibernated machine %s", civoProvider.Config.MachineID)
+	return Create(ctx, civoProvider)
+}
+
+// isLocalInstance returns if the provider runs on the instance itself.
+func isLocalInstance(instance *civogo.Instance) bool {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+
+	for _, address := range addresses {
+		ipNet, ok := address.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		ip := ipNet.IP.String()
+		if ip != "" && (ip == instance.PrivateIP || ip == instance.PublicIP) {
+			return true
+		}
+	}
+
+	return false
+}
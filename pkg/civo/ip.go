@@ -0,0 +1,176 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/civo/civogo"
+	"github.com/pkg/errors"
+)
+
+// reservedIPName returns the reserved IP to use for the machine. "true" uses
+// a reserved IP named after the machine.
+func reservedIPName(civoProvider *CivoProvider) string {
+	if civoProvider.Config.ReservedIP == "true" {
+		return civoProvider.Config.MachineID
+	}
+
+	return civoProvider.Config.ReservedIP
+}
+
+// GetDevpodReservedIP returns the reserved IP of the machine, if it exists.
+func GetDevpodReservedIP(ctx context.Context, civoProvider *CivoProvider) (*civogo.IP, error) {
+	name := reservedIPName(civoProvider)
+	if name == "" {
+		return nil, nil
+	}
+
+	ip, err := retryValue(ctx, civoProvider.Log, "find reserved ip", func() (*civogo.IP, error) {
+		return civoProvider.Client.FindIP(name)
+	})
+	if err != nil {
+		if errors.Is(err, civogo.ZeroMatchesError) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "find reserved ip %s", name)
+	}
+
+	// FindIP also returns partial matches
+	if ip.Name != name && ip.IP != name && ip.ID != name {
+		return nil, nil
+	}
+
+	return ip, nil
+}
+
+// assignReservedIP binds the reserved IP of the machine to the instance,
+// allocating it first if it doesn't exist yet.
+func assignReservedIP(ctx context.Context, civoProvider *CivoProvider, instanceID string, journal *createJournal) error {
+	ip, err := GetDevpodReservedIP(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	if ip == nil {
+		name := reservedIPName(civoProvider)
+		civoProvider.Log.Infof("Allocating reserved ip %s", name)
+		ip, err = createOnce(ctx, civoProvider.Log, "reserved ip",
+			func() (*civogo.IP, error) {
+				return civoProvider.Client.NewIP(&civogo.CreateIPRequest{
+					Name:   name,
+					Region: civoProvider.Config.Region,
+				})
+			},
+			func() (*civogo.IP, bool, error) {
+				ip, err := GetDevpodReservedIP(ctx, civoProvider)
+				return ip, ip != nil, err
+			},
+		)
+		if err != nil {
+			return errors.Wrapf(err, "allocate reserved ip %s", name)
+		}
+
+		// only a reserved ip allocated for the machine is released with it
+		err = updateMachineState(civoProvider.Config.MachineFolder, func(state *MachineState) {
+			state.ReservedIPID = ip.ID
+		})
+		if err != nil {
+			return err
+		}
+
+		journal.record("reserved ip "+name, func(ctx context.Context) error {
+			return unassignReservedIP(ctx, civoProvider, instanceID, true)
+		})
+	} else if ip.AssignedTo.ID == instanceID {
+		return nil
+	} else if ip.AssignedTo.ID != "" {
+		return fmt.Errorf("reserved ip %s is already assigned to %s %s", ip.Name, ip.AssignedTo.Type, ip.AssignedTo.Name)
+	} else {
+		journal.record("reserved ip assignment "+ip.Name, func(ctx context.Context) error {
+			return unassignReservedIP(ctx, civoProvider, instanceID, false)
+		})
+	}
+
+	err = retry(ctx, civoProvider.Log, "assign reserved ip", func() error {
+		_, err := civoProvider.Client.AssignIP(ip.ID, instanceID, "instance", civoProvider.Config.Region)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "assign reserved ip %s", ip.Name)
+	}
+
+	return nil
+}
+
+// unassignReservedIP unbinds the reserved IP from the instance of the machine.
+// If release is set, it's released as well if it was allocated for the
+// machine. A reserved IP assigned to another resource is left alone.
+func unassignReservedIP(ctx context.Context, civoProvider *CivoProvider, instanceID string, release bool) error {
+	ip, err := GetDevpodReservedIP(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	if ip == nil {
+		return nil
+	}
+
+	if instanceID != "" && ip.AssignedTo.ID == instanceID {
+		err = retry(ctx, civoProvider.Log, "unassign reserved ip", func() error {
+			_, err := civoProvider.Client.UnassignIP(ip.ID, civoProvider.Config.Region)
+			return err
+		})
+		if err != nil && !IsNotFound(err) {
+			return errors.Wrapf(err, "unassign reserved ip %s", ip.Name)
+		}
+
+		ip.AssignedTo = civogo.AssignedTo{}
+	}
+
+	if !release {
+		return nil
+	}
+
+	state, err := LoadMachineState(civoProvider.Config.MachineFolder)
+	if err != nil {
+		return err
+	}
+
+	if state == nil || state.ReservedIPID != ip.ID {
+		civoProvider.Log.Infof("Keeping reserved ip %s, it wasn't allocated for machine %s", ip.Name, civoProvider.Config.MachineID)
+		return nil
+	} else if ip.AssignedTo.ID != "" {
+		civoProvider.Log.Warnf("Keeping reserved ip %s, it is assigned to %s %s", ip.Name, ip.AssignedTo.Type, ip.AssignedTo.Name)
+		return nil
+	}
+
+	return releaseReservedIP(ctx, civoProvider, ip)
+}
+
+// deleteOrphanedReservedIP releases the reserved IP named after a machine that
+// doesn't exist anymore, unless it was assigned to another resource since.
+func deleteOrphanedReservedIP(ctx context.Context, civoProvider *CivoProvider) error {
+	ip, err := GetDevpodReservedIP(ctx, civoProvider)
+	if err != nil || ip == nil {
+		return err
+	}
+
+	if ip.AssignedTo.ID != "" {
+		return fmt.Errorf("reserved ip %s is assigned to %s %s", ip.Name, ip.AssignedTo.Type, ip.AssignedTo.Name)
+	}
+
+	return releaseReservedIP(ctx, civoProvider, ip)
+}
+
+func releaseReservedIP(ctx context.Context, civoProvider *CivoProvider, ip *civogo.IP) error {
+	err := retry(ctx, civoProvider.Log, "release reserved ip", func() error {
+		_, err := civoProvider.Client.DeleteIP(ip.ID)
+		return err
+	})
+	if err != nil && !IsNotFound(err) {
+		return errors.Wrapf(err, "release reserved ip %s", ip.Name)
+	}
+
+	return nil
+}
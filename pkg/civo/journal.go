@@ -0,0 +1,70 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rollbackTimeout bounds the cleanup after a failed create. It doesn't use the
+// context of the create, which is likely cancelled already.
+const rollbackTimeout = 5 * time.Minute
+
+// createJournal records how to undo every resource a create makes, so a failed
+// or interrupted create doesn't leave orphaned resources behind. Steps are only
+// recorded once the create call returned the resource and only remove that
+// resource, so a concurrent create of the same machine never loses its
+// resources. If the result of a create call is lost, the resource is left
+// behind for the next create, which reuses it.
+type createJournal struct {
+	steps []rollbackStep
+}
+
+type rollbackStep struct {
+	what string
+	undo func(ctx context.Context) error
+}
+
+func (j *createJournal) record(what string, undo func(ctx context.Context) error) {
+	j.steps = append(j.steps, rollbackStep{what: what, undo: undo})
+}
+
+// rollback undoes the recorded steps in reverse order and returns the error
+// that caused the rollback, noting the resources that couldn't be removed.
+func (j *createJournal) rollback(civoProvider *CivoProvider, cause error) error {
+	if len(j.steps) == 0 {
+		return cause
+	}
+
+	civoProvider.Log.Warnf("Creating machine %s failed, removing the resources created so far: %v", civoProvider.Config.MachineID, cause)
+
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	failed := j.undo(ctx, civoProvider)
+	if len(failed) > 0 {
+		return fmt.Errorf("%w\nrolling back failed, please remove these manually: %s", cause, strings.Join(failed, ", "))
+	}
+
+	return cause
+}
+
+// undo runs the recorded steps in reverse order, forgets them and returns the
+// resources that couldn't be removed.
+func (j *createJournal) undo(ctx context.Context, civoProvider *CivoProvider) []string {
+	failed := []string{}
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		step := j.steps[i]
+		civoProvider.Log.Debugf("Rolling back %s", step.what)
+
+		err := step.undo(ctx)
+		if err != nil {
+			civoProvider.Log.Warnf("Error rolling back %s: %v", step.what, err)
+			failed = append(failed, step.what)
+		}
+	}
+
+	j.steps = nil
+	return failed
+}
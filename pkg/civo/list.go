@@ -0,0 +1,144 @@
+package civo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// MachineListEntry is a DevPod instance with its owner and estimated cost.
+type MachineListEntry struct {
+	*InstanceDetails
+	Machine     string  `json:"machine"`
+	Owner       string  `json:"owner,omitempty"`
+	HourlyPrice float64 `json:"hourlyPrice"`
+	PriceKnown  bool    `json:"priceKnown"`
+	PriceSource string  `json:"priceSource,omitempty"`
+}
+
+// ListMachines returns the DevPod instances in all regions of the account,
+// only the ones of owner if it is set. Regions that can't be listed are
+// skipped with a warning.
+func ListMachines(ctx context.Context, client *civogo.Client, logs log.Logger, owner string) ([]MachineListEntry, error) {
+	regions, err := retryValue(ctx, logs, "list regions", client.ListRegions)
+	if err != nil {
+		return nil, errors.Wrap(err, "list regions")
+	}
+
+	// the client is shared, so restore the region it was created for
+	defaultRegion := client.Region
+	defer func() {
+		client.Region = defaultRegion
+	}()
+
+	entries := []MachineListEntry{}
+	for _, region := range regions {
+		client.Region = region.Code
+		instances, err := ListDevpodInstances(ctx, client, logs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+
+			logs.Warnf("Error listing instances in region %s: %v", region.Code, err)
+			continue
+		}
+
+		for i := range instances {
+			instance := &instances[i]
+			if owner != "" && TagValue(instance.Tags, OwnerTagPrefix) != sanitizeTag(owner) {
+				continue
+			}
+
+			entry := MachineListEntry{
+				InstanceDetails: NewInstanceDetails(instance),
+				Machine:         TagValue(instance.Tags, MachineTagPrefix),
+				Owner:           TagValue(instance.Tags, OwnerTagPrefix),
+			}
+			if entry.Machine == "" {
+				entry.Machine = instance.Hostname
+			}
+			if entry.Region == "" {
+				entry.Region = region.Code
+			}
+
+			// civo bills stopped instances as well
+			entry.HourlyPrice, entry.PriceSource = HourlyPrice(instance.Size)
+			entry.PriceKnown = entry.PriceSource != ""
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Region != entries[j].Region {
+			return entries[i].Region < entries[j].Region
+		}
+
+		return entries[i].Machine < entries[j].Machine
+	})
+
+	return entries, nil
+}
+
+// PrintMachinesJSON writes the machines as indented json.
+func PrintMachinesJSON(out io.Writer, entries []MachineListEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// PrintMachines writes the machines as a table, followed by the total
+// estimated hourly cost.
+func PrintMachines(out io.Writer, entries []MachineListEntry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MACHINE\tOWNER\tREGION\tSTATUS\tSIZE\tIP\tAGE\tCOST/HOUR")
+
+	total := 0.0
+	sources := map[string]bool{}
+	for _, entry := range entries {
+		ip := entry.PublicIP
+		if ip == "" {
+			ip = entry.PrivateIP
+		}
+		if ip == "" {
+			ip = "-"
+		}
+
+		age := "-"
+		if entry.CreatedAt != nil {
+			age = formatAge(time.Since(*entry.CreatedAt))
+		}
+
+		price := "unknown"
+		if entry.PriceKnown {
+			price = fmt.Sprintf("$%.4f", entry.HourlyPrice)
+			total += entry.HourlyPrice
+		}
+		if entry.PriceSource == PriceSourceTable {
+			price += "*"
+		}
+		sources[entry.PriceSource] = true
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Machine, entry.Owner, entry.Region, entry.State, entry.Size, ip, age, price)
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "%d machines, estimated $%.4f per hour (USD list prices)\n", len(entries), total)
+	if note := priceNote(sources); note != "" {
+		fmt.Fprintln(w, note)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,83 @@
+package civo
+
+import (
+	"context"
+
+	"github.com/civo/civogo"
+	"github.com/pkg/errors"
+)
+
+// resolveNetwork returns the ID of CIVO_NETWORK, creating it if it doesn't
+// exist and CIVO_NETWORK_CREATE is enabled. Without CIVO_NETWORK the default
+// network is used.
+func resolveNetwork(ctx context.Context, civoProvider *CivoProvider, defaultNetworkID string) (string, error) {
+	if civoProvider.Config.Network == "" {
+		return defaultNetworkID, nil
+	}
+
+	network, err := retryValue(ctx, civoProvider.Log, "find network", func() (*civogo.Network, error) {
+		return findNetwork(civoProvider.Client, civoProvider.Config.Network)
+	})
+	if err == nil {
+		return network.ID, nil
+	} else if !errors.Is(err, civogo.ZeroMatchesError) || !civoProvider.Config.CreateNetwork {
+		return "", errors.Wrapf(err, "find network %s", civoProvider.Config.Network)
+	}
+
+	civoProvider.Log.Infof("Creating network %s", civoProvider.Config.Network)
+	networkID, err := createOnce(ctx, civoProvider.Log, "network",
+		func() (string, error) {
+			result, err := civoProvider.Client.NewNetwork(civoProvider.Config.Network)
+			if err != nil {
+				return "", err
+			}
+
+			return result.ID, nil
+		},
+		func() (string, bool, error) {
+			network, err := findNetwork(civoProvider.Client, civoProvider.Config.Network)
+			if errors.Is(err, civogo.ZeroMatchesError) {
+				return "", false, nil
+			} else if err != nil {
+				return "", false, err
+			}
+
+			return network.ID, true, nil
+		},
+	)
+	if err != nil {
+		return "", errors.Wrapf(err, "create network %s", civoProvider.Config.Network)
+	}
+
+	return networkID, nil
+}
+
+// findNetwork returns the network with the exact name, label or ID.
+func findNetwork(client civogo.Clienter, search string) (*civogo.Network, error) {
+	network, err := client.FindNetwork(search)
+	if errors.Is(err, civogo.MultipleMatchesError) {
+		return nil, errors.Wrapf(civogo.ZeroMatchesError, "no exact match for %s", search)
+	} else if err != nil {
+		return nil, err
+	}
+
+	// FindNetwork also returns partial matches
+	if network.Name != search && network.Label != search && network.ID != search {
+		return nil, errors.Wrapf(civogo.ZeroMatchesError, "no exact match for %s", search)
+	}
+
+	return network, nil
+}
+
+// InstanceAddress returns the address to reach the instance at, which is the
+// private IP if the instance was created without a public IP and otherwise
+// the reserved IP if one is assigned.
+func InstanceAddress(instance *civogo.Instance, usePrivateIP bool) string {
+	if usePrivateIP {
+		return instance.PrivateIP
+	} else if instance.ReservedIP != "" {
+		return instance.ReservedIP
+	}
+
+	return instance.PublicIP
+}
@@ -0,0 +1,573 @@
+package civo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/hash"
+	"github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PoolTag is set on idle instances of the warm pool.
+	PoolTag = "devpod-pool"
+
+	// PoolSpecTagPrefix tags pool instances with a hash of the options they
+	// were created with, only instances with the same options are claimed.
+	PoolSpecTagPrefix = "devpod-pool-spec:"
+
+	// PoolClaimTagPrefix marks a pool instance as being claimed by a machine.
+	PoolClaimTagPrefix = "devpod-pool-claim:"
+)
+
+const (
+	// poolClaimSettle is how long a claim has to survive before it counts,
+	// see claimCandidate.
+	poolClaimSettle = 3 * time.Second
+
+	// poolClaimTimeout is after how long an unfinished claim is considered
+	// abandoned, so the instance can be reaped.
+	poolClaimTimeout = time.Hour
+)
+
+// FillPool reaps pool instances idle for longer than ttl and creates instances
+// until size idle instances with the current options are available. It
+// returns the number of created instances.
+func FillPool(ctx context.Context, civoProvider *CivoProvider, size int, ttl time.Duration) (int, error) {
+	_, err := ReapPool(ctx, civoProvider, ttl)
+	if err != nil {
+		return 0, err
+	}
+
+	config, spec, err := poolInstanceConfig(ctx, civoProvider)
+	if err != nil {
+		return 0, err
+	}
+
+	idle, err := idlePoolInstances(ctx, civoProvider, spec)
+	if err != nil {
+		return 0, err
+	}
+
+	missing := size - len(idle)
+	if missing <= 0 {
+		civoProvider.Log.Infof("Pool already has %d idle instances", len(idle))
+		return 0, nil
+	}
+
+	pool := poolProvider(civoProvider, spec)
+	journal := &createJournal{}
+	err = fillPool(ctx, pool, config, spec, missing, journal)
+	if err != nil {
+		return 0, journal.rollback(pool, err)
+	}
+
+	return missing, nil
+}
+
+func fillPool(ctx context.Context, pool *CivoProvider, config *civogo.InstanceConfig, spec string, count int, journal *createJournal) error {
+	// check the quota for all instances, so a fill doesn't create some and
+	// then roll them back
+	err := checkQuota(ctx, pool, count)
+	if err != nil {
+		return err
+	}
+
+	config.SSHKeyID, err = ensureSSHKey(ctx, pool, journal)
+	if err != nil {
+		return err
+	}
+
+	config.FirewallID, err = ensureFirewall(ctx, pool, config.NetworkID, journal)
+	if err != nil {
+		return err
+	}
+
+	config.Script, err = RenderScript(ScriptData{
+		MachineID:   pool.Config.MachineID,
+		Region:      pool.Config.Region,
+		InitialUser: InitialUser,
+		AgentPath:   pool.Config.AgentPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	config.Tags = []string{DevpodTag, PoolTag, PoolSpecTagPrefix + spec}
+	if pool.Config.Owner != "" {
+		config.Tags = append(config.Tags, OwnerTag(pool.Config.Owner))
+	}
+
+	ids := []string{}
+	for i := 0; i < count; i++ {
+		suffix, err := randomSuffix()
+		if err != nil {
+			return err
+		}
+
+		instanceConfig := *config
+		instanceConfig.Hostname = pool.Config.MachineID + "-" + suffix
+		pool.Log.Infof("Creating pool instance %s", instanceConfig.Hostname)
+
+		instance, err := createOnce(ctx, pool.Log, "pool instance "+instanceConfig.Hostname,
+			func() (*civogo.Instance, error) {
+				return pool.Client.CreateInstance(&instanceConfig)
+			},
+			func() (*civogo.Instance, bool, error) {
+				// the random suffix makes the hostname unique to this attempt
+				instances, err := listAllInstances(ctx, pool.Client, pool.Log)
+				if err != nil {
+					return nil, false, err
+				}
+
+				for i := range instances {
+					if instances[i].Hostname == instanceConfig.Hostname {
+						return &instances[i], true, nil
+					}
+				}
+
+				return nil, false, nil
+			},
+		)
+		if err != nil {
+			return errors.Wrapf(err, "create pool instance %s", instanceConfig.Hostname)
+		}
+
+		instanceID := instance.ID
+		journal.record("instance "+instanceConfig.Hostname, func(ctx context.Context) error {
+			return deleteInstance(ctx, pool, instanceID)
+		})
+		ids = append(ids, instanceID)
+	}
+
+	for _, id := range ids {
+		_, err = WaitForInstance(ctx, pool.Client, id, waitOptions(pool), pool.Log)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReapPool deletes pool instances that were idle for longer than ttl, as well
+// as instances whose claim was abandoned, and the firewall and ssh key of pools
+// without instances. It returns the number of deleted instances.
+func ReapPool(ctx context.Context, civoProvider *CivoProvider, ttl time.Duration) (int, error) {
+	instances, err := listAllInstances(ctx, civoProvider.Client, civoProvider.Log)
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	specs := map[string]bool{}
+	for _, instance := range instances {
+		if !hasTag(instance.Tags, PoolTag) {
+			continue
+		}
+
+		spec := TagValue(instance.Tags, PoolSpecTagPrefix)
+		if instance.CreatedAt.IsZero() {
+			specs[spec] = true
+			continue
+		}
+
+		maxAge := ttl
+		if TagValue(instance.Tags, PoolClaimTagPrefix) != "" {
+			maxAge += poolClaimTimeout
+		}
+
+		if time.Since(instance.CreatedAt) < maxAge {
+			specs[spec] = true
+			continue
+		}
+
+		civoProvider.Log.Infof("Deleting pool instance %s, it was idle since %s", instance.Hostname, instance.CreatedAt.Format(time.RFC3339))
+		err = deleteInstance(ctx, civoProvider, instance.ID)
+		if err != nil {
+			return reaped, err
+		}
+
+		reaped++
+	}
+
+	return reaped, deleteEmptyPools(ctx, civoProvider, specs)
+}
+
+// deleteEmptyPools deletes the firewalls and ssh keys of the pools whose spec
+// isn't in specs, the specs that still have instances. gc leaves them alone.
+func deleteEmptyPools(ctx context.Context, civoProvider *CivoProvider, specs map[string]bool) error {
+	isEmptyPool := func(name string) bool {
+		return strings.HasPrefix(name, PoolTag+"-") && !specs[strings.TrimPrefix(name, PoolTag+"-")]
+	}
+
+	firewalls, err := retryValue(ctx, civoProvider.Log, "list firewalls", civoProvider.Client.ListFirewalls)
+	if err != nil {
+		return errors.Wrap(err, "list firewalls")
+	}
+
+	for _, firewall := range firewalls {
+		if isEmptyPool(firewall.Name) {
+			civoProvider.Log.Infof("Deleting firewall %s of the empty pool", firewall.Name)
+			err = deleteCreatedFirewall(ctx, civoProvider, firewall.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	sshKeys, err := retryValue(ctx, civoProvider.Log, "list ssh keys", civoProvider.Client.ListSSHKeys)
+	if err != nil {
+		return errors.Wrap(err, "list ssh keys")
+	}
+
+	for _, sshKey := range sshKeys {
+		if isEmptyPool(sshKey.Name) {
+			civoProvider.Log.Infof("Deleting ssh key %s of the empty pool", sshKey.Name)
+			err = removeSSHKey(ctx, civoProvider, sshKey.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// claimFromPool turns an idle pool instance with matching options into the
+// instance of the machine. It returns false if there is no such instance and
+// the machine has to be created instead.
+func claimFromPool(ctx context.Context, civoProvider *CivoProvider, journal *createJournal) (bool, error) {
+	volumeSizeGB, err := dataVolumeSizeGB(ctx, civoProvider)
+	if err != nil {
+		return false, err
+	} else if volumeSizeGB > 0 {
+		// the data volume has to be mounted before docker is installed
+		civoProvider.Log.Debugf("Not using the pool, pool instances have no data volume")
+		return false, nil
+	}
+
+	_, spec, err := poolInstanceConfig(ctx, civoProvider)
+	if err != nil {
+		return false, err
+	}
+
+	instance, err := claimPoolInstance(ctx, civoProvider, spec)
+	if err != nil {
+		return false, err
+	} else if instance == nil {
+		civoProvider.Log.Infof("No idle pool instance available, creating a new instance")
+		return false, nil
+	}
+
+	civoProvider.Log.Infof("Claimed pool instance %s", instance.Hostname)
+	journal.record("instance "+instance.Hostname, func(ctx context.Context) error {
+		return deleteInstance(ctx, civoProvider, instance.ID)
+	})
+
+	err = saveInstanceSize(civoProvider, instance.ID, instance.Size)
+	if err != nil {
+		return true, err
+	}
+
+	firewallID, err := ensureFirewall(ctx, civoProvider, instance.NetworkID, journal)
+	if err != nil {
+		return true, err
+	}
+
+	err = retry(ctx, civoProvider.Log, "set instance firewall", func() error {
+		_, err := civoProvider.Client.SetInstanceFirewall(instance.ID, firewallID)
+		return err
+	})
+	if err != nil {
+		return true, errors.Wrap(err, "set instance firewall")
+	}
+
+	err = prepareClaimedInstance(ctx, civoProvider, instance)
+	if err != nil {
+		return true, err
+	}
+
+	instance.Hostname = civoProvider.Config.MachineID
+	instance.Notes = instanceNotes(civoProvider)
+	err = retry(ctx, civoProvider.Log, "update instance", func() error {
+		_, err := civoProvider.Client.UpdateInstance(instance)
+		return err
+	})
+	if err != nil {
+		return true, errors.Wrap(err, "update instance")
+	}
+
+	err = retry(ctx, civoProvider.Log, "set instance tags", func() error {
+		_, err := civoProvider.Client.SetInstanceTags(instance, strings.Join(instanceTags(civoProvider), " "))
+		return err
+	})
+	if err != nil {
+		return true, errors.Wrap(err, "set instance tags")
+	}
+
+	if civoProvider.Config.ReservedIP != "" {
+		err = assignReservedIP(ctx, civoProvider, instance.ID, journal)
+		if err != nil {
+			return true, err
+		}
+
+		_, err = WaitForInstance(ctx, civoProvider.Client, instance.ID, waitOptions(civoProvider), civoProvider.Log)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// claimPoolInstance claims the oldest idle pool instance with the spec by
+// tagging it for the machine. It returns nil if there is none.
+func claimPoolInstance(ctx context.Context, civoProvider *CivoProvider, spec string) (*civogo.Instance, error) {
+	idle, err := idlePoolInstances(ctx, civoProvider, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range idle {
+		instance, err := claimCandidate(ctx, civoProvider, candidate.ID, spec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "claim pool instance %s", candidate.Hostname)
+		} else if instance != nil {
+			return instance, nil
+		}
+
+		civoProvider.Log.Debugf("Pool instance %s was claimed concurrently", candidate.Hostname)
+	}
+
+	return nil, nil
+}
+
+// claimCandidate tags the pool instance with the claim of the machine and
+// returns it if the claim won, or nil if it was claimed concurrently.
+//
+// Civo has no conditional updates, so concurrent claims overwrite each other's
+// tags. The claim only writes its tag if the instance is still unclaimed right
+// before, and only if that check and the write took less than
+// poolClaimSettle. It then waits poolClaimSettle and wins if its tag is still
+// there. Another claim either checked after the tag was written and backs off,
+// or wrote its own tag before the read back, so at most one claim wins.
+func claimCandidate(ctx context.Context, civoProvider *CivoProvider, instanceID, spec string) (*civogo.Instance, error) {
+	checked := time.Now()
+	instance, err := retryValue(ctx, civoProvider.Log, "get instance", func() (*civogo.Instance, error) {
+		return civoProvider.Client.GetInstance(instanceID)
+	})
+	if IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if !isIdlePoolInstance(instance, spec) {
+		return nil, nil
+	}
+
+	tags := append(instance.Tags, PoolClaimTagPrefix+civoProvider.Config.MachineID)
+	err = retry(ctx, civoProvider.Log, "claim pool instance", func() error {
+		_, err := civoProvider.Client.SetInstanceTags(instance, strings.Join(tags, " "))
+		return err
+	})
+	if IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if time.Since(checked) >= poolClaimSettle {
+		// a concurrent claim could have checked before the tag was written and
+		// still be within its settle time, the tag is left for the reaper
+		civoProvider.Log.Debugf("Giving up the claim of pool instance %s, tagging it took longer than %s", instance.Hostname, poolClaimSettle)
+		return nil, nil
+	}
+
+	err = sleep(ctx, poolClaimSettle)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err = retryValue(ctx, civoProvider.Log, "get instance", func() (*civogo.Instance, error) {
+		return civoProvider.Client.GetInstance(instanceID)
+	})
+	if IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if TagValue(instance.Tags, PoolClaimTagPrefix) != civoProvider.Config.MachineID || instance.Status != StatusActive {
+		return nil, nil
+	}
+
+	return instance, nil
+}
+
+// idlePoolInstances returns the unclaimed, running pool instances with the
+// spec, oldest first.
+func idlePoolInstances(ctx context.Context, civoProvider *CivoProvider, spec string) ([]civogo.Instance, error) {
+	instances, err := listAllInstances(ctx, civoProvider.Client, civoProvider.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	idle := []civogo.Instance{}
+	for _, instance := range instances {
+		if isIdlePoolInstance(&instance, spec) {
+			idle = append(idle, instance)
+		}
+	}
+
+	sort.Slice(idle, func(i, j int) bool {
+		return createdBefore(&idle[i], &idle[j])
+	})
+
+	return idle, nil
+}
+
+// isIdlePoolInstance returns if the instance is an unclaimed, running pool
+// instance with the spec.
+func isIdlePoolInstance(instance *civogo.Instance, spec string) bool {
+	if !hasTag(instance.Tags, PoolTag) || !hasTag(instance.Tags, PoolSpecTagPrefix+spec) {
+		return false
+	}
+
+	return TagValue(instance.Tags, PoolClaimTagPrefix) == "" && instance.Status == StatusActive
+}
+
+// prepareClaimedInstance logs into the pool instance with the DevPod key it
+// was created with, runs the startup script of the machine and replaces the
+// authorized keys with the key of the machine.
+func prepareClaimedInstance(ctx context.Context, civoProvider *CivoProvider, instance *civogo.Instance) error {
+	script, err := RenderScript(machineScriptData(civoProvider, false))
+	if err != nil {
+		return err
+	}
+
+	publicKeyBase, err := ssh.GetPublicKeyBase(civoProvider.Config.MachineFolder)
+	if err != nil {
+		return errors.Wrap(err, "get public key")
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(publicKeyBase)
+	if err != nil {
+		return errors.Wrap(err, "decode public key")
+	}
+
+	poolKey, err := ssh.GetDevPodPrivateKeyRaw()
+	if err != nil {
+		return errors.Wrap(err, "load pool private key")
+	}
+
+	address := InstanceAddress(instance, !civoProvider.Config.PublicIP)
+	sshClient, err := ssh.NewSSHClient(InitialUser, address+":22", poolKey)
+	if err != nil {
+		return errors.Wrapf(err, "connect to pool instance %s", instance.Hostname)
+	}
+	defer sshClient.Close()
+
+	// the pool instance might still be running its own startup script
+	commands := []struct {
+		what    string
+		command string
+		stdin   string
+	}{
+		{"wait for startup script", "command -v cloud-init >/dev/null 2>&1 && sudo cloud-init status --wait >/dev/null 2>&1 || true", ""},
+		{"run startup script", "sudo sh -s", script},
+		{"authorize machine key", "mkdir -p ~/.ssh && cat > ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys", strings.TrimSpace(string(publicKey)) + "\n"},
+	}
+	for _, command := range commands {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		err = ssh.Run(ctx, sshClient, command.command, strings.NewReader(command.stdin), stdout, stderr)
+		if err != nil {
+			return fmt.Errorf("%s on pool instance %s: %w: %s", command.what, instance.Hostname, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	return nil
+}
+
+// poolInstanceConfig returns the instance config pool instances are created
+// with and the spec hash of the options it depends on.
+func poolInstanceConfig(ctx context.Context, civoProvider *CivoProvider) (*civogo.InstanceConfig, string, error) {
+	image, err := ResolveDiskImage(ctx, civoProvider.Client, civoProvider.Log, civoProvider.Config.DiskImage, civoProvider.Config.Region)
+	if err != nil {
+		return nil, "", err
+	}
+
+	config, err := retryValue(ctx, civoProvider.Log, "get instance defaults", civoProvider.Client.NewInstanceConfig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	config.PublicIPRequired = "true"
+	if !civoProvider.Config.PublicIP {
+		config.PublicIPRequired = "none"
+	}
+	config.Count = 1
+	config.Size = civoProvider.Config.MachineType
+	config.Region = civoProvider.Config.Region
+	config.InitialUser = InitialUser
+	config.TemplateID = image.ID
+
+	config.NetworkID, err = resolveNetwork(ctx, civoProvider, config.NetworkID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// pool instances are handed over with the DevPod key of the user who
+	// filled the pool, so every key gets its own pool and pool ssh key and
+	// fills of other users don't replace it
+	publicKey, err := ssh.GetDevPodPublicKey()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "get pool public key")
+	}
+
+	spec := hash.String(strings.Join([]string{
+		config.Region,
+		config.Size,
+		config.TemplateID,
+		config.NetworkID,
+		config.PublicIPRequired,
+		publicKey,
+	}, "/"))[:12]
+
+	return config, spec, nil
+}
+
+// poolProvider returns the provider for the pool resources of the spec. The
+// pool instances are created with the DevPod key of the local user, which is
+// used to hand them over to a machine, and without a data volume.
+func poolProvider(civoProvider *CivoProvider, spec string) *CivoProvider {
+	config := *civoProvider.Config
+	config.MachineID = PoolTag + "-" + spec
+	config.MachineFolder = ssh.GetDevPodKeysDir()
+	config.DiskSizeGB = 0
+	config.VolumeSizeGB = 0
+
+	return &CivoProvider{
+		Config: &config,
+		Client: civoProvider.Client,
+		Log:    civoProvider.Log,
+	}
+}
+
+func randomSuffix() (string, error) {
+	b := make([]byte, 3)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", errors.Wrap(err, "generate instance name")
+	}
+
+	return hex.EncodeToString(b), nil
+}
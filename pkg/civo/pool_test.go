@@ -0,0 +1,56 @@
+package civo
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/civo/civogo"
+)
+
+func TestReapPoolDeletesEmptyPools(t *testing.T) {
+	client := newFakeClient(t)
+	civoProvider := newTestProvider(client, t.TempDir())
+
+	// pool "idle" keeps an instance, pool "expired" loses its only instance
+	// and pool "empty" has none left
+	for _, spec := range []string{"idle", "expired", "empty"} {
+		_, err := client.NewSSHKey(PoolTag+"-"+spec, "ssh-ed25519 AAAA")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = client.NewFirewall(&civogo.FirewallConfig{Name: PoolTag + "-" + spec})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client.Instances = []civogo.Instance{
+		{ID: "instance-1", Hostname: "devpod-pool-idle-1", Status: StatusActive, CreatedAt: time.Now(), Tags: []string{DevpodTag, PoolTag, PoolSpecTagPrefix + "idle"}},
+		{ID: "instance-2", Hostname: "devpod-pool-expired-1", Status: StatusActive, CreatedAt: time.Now().Add(-2 * time.Hour), Tags: []string{DevpodTag, PoolTag, PoolSpecTagPrefix + "expired"}},
+	}
+
+	reaped, err := ReapPool(context.Background(), civoProvider, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reaped != 1 || len(client.Instances) != 1 || client.Instances[0].ID != "instance-1" {
+		t.Errorf("expected only the expired instance to be reaped, reaped %d and kept %+v", reaped, client.Instances)
+	}
+
+	names := []string{}
+	for _, sshKey := range client.SSHKeys {
+		names = append(names, "ssh key "+sshKey.Name)
+	}
+	for _, firewall := range client.Firewalls {
+		names = append(names, "firewall "+firewall.Name)
+	}
+	sort.Strings(names)
+
+	if len(names) != 2 || names[0] != "firewall devpod-pool-idle" || names[1] != "ssh key devpod-pool-idle" {
+		t.Errorf("expected only the resources of the idle pool to be kept, got %v", names)
+	}
+}
@@ -0,0 +1,87 @@
+package civo
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// hoursPerMonth is what civo bills a month of usage as.
+const hoursPerMonth = 730
+
+// PricesEnv overrides or adds monthly prices in USD, e.g.
+// "g4s.small=12,g3.large=40". Entries that don't parse are ignored.
+const PricesEnv = "CIVO_PRICES"
+
+// Where the price of a size comes from, a price without a source is unknown.
+const (
+	// PriceSourceTable is a price of monthlyPrices, which can be outdated
+	PriceSourceTable = "table"
+
+	// PriceSourceEnv is a price set with PricesEnv
+	PriceSourceEnv = "env"
+)
+
+// monthlyPrices are civo's list prices in USD per month for instance sizes at
+// the time of writing. The sizes api doesn't return prices, so they go stale
+// when civo changes prices or adds sizes, which PricesEnv works around until
+// the table is updated.
+var monthlyPrices = map[string]float64{
+	"g3.xsmall":  5,
+	"g3.small":   10,
+	"g3.medium":  20,
+	"g3.large":   40,
+	"g3.xlarge":  80,
+	"g3.2xlarge": 160,
+}
+
+// volumePricePerGBMonth is the list price in USD of block storage.
+const volumePricePerGBMonth = 0.10
+
+// MonthlyPrice returns the price of the size in USD per month and where it
+// comes from, the source is empty if the price is unknown.
+func MonthlyPrice(size string) (float64, string) {
+	size = strings.ToLower(size)
+	for _, entry := range strings.Split(os.Getenv(PricesEnv), ",") {
+		name, value, found := strings.Cut(entry, "=")
+		if !found || strings.ToLower(strings.TrimSpace(name)) != size {
+			continue
+		}
+
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err == nil && price >= 0 {
+			return price, PriceSourceEnv
+		}
+	}
+
+	price, ok := monthlyPrices[size]
+	if !ok {
+		return 0, ""
+	}
+
+	return price, PriceSourceTable
+}
+
+// HourlyPrice returns the price of the size in USD per hour and where it comes
+// from, the source is empty if the price is unknown.
+func HourlyPrice(size string) (float64, string) {
+	price, source := MonthlyPrice(size)
+	return price / hoursPerMonth, source
+}
+
+// priceNote explains the prices of the sources in a table footer, the table
+// marks prices from the built-in table with a "*".
+func priceNote(sources map[string]bool) string {
+	notes := []string{}
+	if sources[PriceSourceTable] {
+		notes = append(notes, "* built-in list price, which can be outdated")
+	}
+	if sources[""] {
+		notes = append(notes, "sizes without a built-in price are unknown")
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+
+	return strings.Join(notes, ", ") + ". Set " + PricesEnv + "=SIZE=USD_PER_MONTH,... to correct or add prices"
+}
@@ -0,0 +1,27 @@
+package civo
+
+import "testing"
+
+func TestMonthlyPrice(t *testing.T) {
+	t.Setenv(PricesEnv, "g4s.small=12, G3.Large = 45,g3.medium=cheap")
+
+	tests := []struct {
+		size   string
+		price  float64
+		source string
+	}{
+		{size: "g3.small", price: 10, source: PriceSourceTable},
+		{size: "G3.SMALL", price: 10, source: PriceSourceTable},
+		{size: "g4s.small", price: 12, source: PriceSourceEnv},
+		{size: "g3.large", price: 45, source: PriceSourceEnv},
+		{size: "g3.medium", price: 20, source: PriceSourceTable},
+		{size: "g4p.medium"},
+	}
+
+	for _, test := range tests {
+		price, source := MonthlyPrice(test.size)
+		if price != test.price || source != test.source {
+			t.Errorf("expected %s to cost %.2f from %q, got %.2f from %q", test.size, test.price, test.source, price, source)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/civo/civogo"
+	"github.com/pkg/errors"
+)
+
+// QuotaItem is the usage, limit and requirement of a single quota resource.
+type QuotaItem struct {
+	Name     string `json:"name"`
+	Usage    int    `json:"usage"`
+	Limit    int    `json:"limit"`
+	Required int    `json:"required"`
+}
+
+func (q QuotaItem) Available() int {
+	if q.Limit < q.Usage {
+		return 0
+	}
+
+	return q.Limit - q.Usage
+}
+
+func (q QuotaItem) Exhausted() bool {
+	return q.Required > q.Available()
+}
+
+// QuotaReport compares the account quota against what a new machine needs.
+type QuotaReport struct {
+	Size  string      `json:"size"`
+	Items []QuotaItem `json:"items"`
+
+	// count is the number of instances the requirements are for
+	count int
+}
+
+// GetQuotaReport fetches the account quota and calculates the resources a
+// new instance of the machine size needs, including its data volume unless
+// that exists already.
+func GetQuotaReport(ctx context.Context, civoProvider *CivoProvider) (*QuotaReport, error) {
+	quota, err := retryValue(ctx, civoProvider.Log, "get quota", civoProvider.Client.GetQuota)
+	if err != nil {
+		return nil, errors.Wrap(err, "get quota")
+	}
+
+	sizeName, err := machineSize(civoProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := retryValue(ctx, civoProvider.Log, "find instance size", func() (*civogo.InstanceSize, error) {
+		return civoProvider.Client.FindInstanceSizes(sizeName)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "find instance size %s", sizeName)
+	}
+
+	volumeSizeGB := 0
+	if civoProvider.Config.VolumeSizeGB > 0 {
+		volumeSizeGB = civoProvider.Config.VolumeSizeGB
+	} else if civoProvider.Config.DiskSizeGB > size.DiskGigabytes {
+		volumeSizeGB = civoProvider.Config.DiskSizeGB - size.DiskGigabytes
+	}
+
+	if volumeSizeGB > 0 {
+		// the volume of a previous instance is reattached and already counted
+		volume, err := GetDevpodVolume(ctx, civoProvider)
+		if err != nil {
+			return nil, err
+		} else if volume != nil {
+			volumeSizeGB = 0
+		}
+	}
+
+	publicIPs := 0
+	if civoProvider.Config.PublicIP {
+		publicIPs = 1
+	}
+
+	return newQuotaReport(quota, size, volumeSizeGB, publicIPs), nil
+}
+
+func newQuotaReport(quota *civogo.Quota, size *civogo.InstanceSize, volumeSizeGB, publicIPs int) *QuotaReport {
+	return &QuotaReport{
+		Size:  size.Name,
+		count: 1,
+		Items: []QuotaItem{
+			{Name: "instances", Usage: quota.InstanceCountUsage, Limit: quota.InstanceCountLimit, Required: 1},
+			{Name: "cpu cores", Usage: quota.CPUCoreUsage, Limit: quota.CPUCoreLimit, Required: size.CPUCores},
+			{Name: "ram (MB)", Usage: quota.RAMMegabytesUsage, Limit: quota.RAMMegabytesLimit, Required: size.RAMMegabytes},
+			{Name: "disk (GB)", Usage: quota.DiskGigabytesUsage, Limit: quota.DiskGigabytesLimit, Required: size.DiskGigabytes + volumeSizeGB},
+			{Name: "public ips", Usage: quota.PublicIPAddressUsage, Limit: quota.PublicIPAddressLimit, Required: publicIPs},
+		},
+	}
+}
+
+// forInstances multiplies the requirements of a single instance by count.
+func (r *QuotaReport) forInstances(count int) {
+	for i := range r.Items {
+		r.Items[i].Required *= count
+	}
+
+	r.count = count
+}
+
+// Exhausted returns the quota items that don't allow creating the machine.
+func (r *QuotaReport) Exhausted() []QuotaItem {
+	exhausted := []QuotaItem{}
+	for _, item := range r.Items {
+		if item.Exhausted() {
+			exhausted = append(exhausted, item)
+		}
+	}
+
+	return exhausted
+}
+
+// Err returns an error describing the exhausted quota, if any.
+func (r *QuotaReport) Err() error {
+	exhausted := r.Exhausted()
+	if len(exhausted) == 0 {
+		return nil
+	}
+
+	lines := []string{}
+	for _, item := range exhausted {
+		lines = append(lines, fmt.Sprintf("%s: need %d, but only %d of %d are available", item.Name, item.Required, item.Available(), item.Limit))
+	}
+
+	instances := "a " + r.Size + " instance"
+	if r.count > 1 {
+		instances = fmt.Sprintf("%d %s instances", r.count, r.Size)
+	}
+
+	return fmt.Errorf(
+		"not enough quota to create %s:\n  - %s",
+		instances,
+		strings.Join(lines, "\n  - "),
+	)
+}
+
+// Print writes the report as a table.
+func (r *QuotaReport) Print(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tUSAGE\tLIMIT\tAVAILABLE\tREQUIRED ("+r.Size+")\tOK")
+	for _, item := range r.Items {
+		ok := "yes"
+		if item.Exhausted() {
+			ok = "no"
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", item.Name, item.Usage, item.Limit, item.Available(), item.Required, ok)
+	}
+
+	return w.Flush()
+}
+
+// checkQuota refuses to create count instances of the machine if the account
+// quota is exhausted.
+func checkQuota(ctx context.Context, civoProvider *CivoProvider, count int) error {
+	report, err := GetQuotaReport(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	report.forInstances(count)
+	return report.Err()
+}
@@ -0,0 +1,94 @@
+package civo
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// rebootSettle bounds how long to wait for a rebooting instance to go
+	// down, so it isn't mistaken for being back up already.
+	rebootSettle = time.Minute
+
+	rebootPollInterval = 2 * time.Second
+)
+
+// Reboot reboots the instance of the machine and waits until it is reachable
+// again. A hard reboot resets the instance, which also works if the operating
+// system doesn't respond anymore.
+func Reboot(ctx context.Context, civoProvider *CivoProvider, hard bool) error {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	what, reboot := "soft reboot instance", civoProvider.Client.SoftRebootInstance
+	if hard {
+		what, reboot = "hard reboot instance", civoProvider.Client.HardRebootInstance
+	}
+
+	civoProvider.Log.Infof("Rebooting instance %s", instance.ID)
+	err = retry(ctx, civoProvider.Log, what, func() error {
+		_, err := reboot(instance.ID)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "reboot instance %s", instance.ID)
+	}
+
+	err = waitForReboot(ctx, civoProvider, instance.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = WaitForInstance(ctx, civoProvider.Client, instance.ID, waitOptions(civoProvider), civoProvider.Log)
+	return err
+}
+
+// waitForReboot waits until the instance isn't ACTIVE and reachable anymore,
+// or rebootSettle passed, in case the reboot was too quick to notice.
+func waitForReboot(ctx context.Context, civoProvider *CivoProvider, instanceID string) error {
+	deadline := time.Now().Add(rebootSettle)
+	for time.Now().Before(deadline) {
+		instance, err := retryValue(ctx, civoProvider.Log, "get instance", func() (*civogo.Instance, error) {
+			return civoProvider.Client.GetInstance(instanceID)
+		})
+		if err != nil {
+			return err
+		}
+
+		address := InstanceAddress(instance, !civoProvider.Config.PublicIP)
+		if instance.Status != StatusActive || address == "" || ProbeSSH(net.JoinHostPort(address, sshPort)) != nil {
+			return nil
+		}
+
+		err = sleep(ctx, rebootPollInterval)
+		if err != nil {
+			return err
+		}
+	}
+
+	civoProvider.Log.Debugf("Instance %s didn't go down within %s after the reboot", instanceID, rebootSettle)
+	return nil
+}
+
+// ConsoleURL returns the URL of the web console of the machine's instance.
+func ConsoleURL(ctx context.Context, civoProvider *CivoProvider) (string, error) {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if err != nil {
+		return "", err
+	}
+
+	url, err := retryValue(ctx, civoProvider.Log, "get console url", func() (string, error) {
+		return civoProvider.Client.GetInstanceConsoleURL(instance.ID)
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "get console url of instance %s", instance.ID)
+	}
+
+	return url, nil
+}
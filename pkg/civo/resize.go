@@ -0,0 +1,120 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/civo/civogo"
+	"github.com/pkg/errors"
+)
+
+// Resize changes the instance of the machine to the given size. The instance
+// is shut down for the resize and is running again afterwards.
+func Resize(ctx context.Context, civoProvider *CivoProvider, size string) error {
+	instance, err := GetDevpodInstance(ctx, civoProvider)
+	if err != nil {
+		return err
+	}
+
+	target, err := retryValue(ctx, civoProvider.Log, "find instance size", func() (*civogo.InstanceSize, error) {
+		return civoProvider.Client.FindInstanceSizes(size)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "find instance size %s", size)
+	} else if !target.Selectable {
+		return fmt.Errorf("instance size %s can't be used for new instances", target.Name)
+	}
+
+	if target.Name == instance.Size {
+		civoProvider.Log.Infof("Instance %s already has size %s", instance.ID, target.Name)
+		return saveInstanceSize(civoProvider, instance.ID, target.Name)
+	}
+
+	current, err := retryValue(ctx, civoProvider.Log, "find instance size", func() (*civogo.InstanceSize, error) {
+		return civoProvider.Client.FindInstanceSizes(instance.Size)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "find instance size %s", instance.Size)
+	}
+
+	err = checkResize(current, target)
+	if err != nil {
+		return err
+	}
+
+	civoProvider.Log.Infof("Resizing instance %s from %s to %s", instance.ID, current.Name, target.Name)
+	err = shutdownInstance(ctx, civoProvider, instance)
+	if err != nil {
+		return err
+	}
+
+	err = retry(ctx, civoProvider.Log, "resize instance", func() error {
+		_, err := civoProvider.Client.UpgradeInstance(instance.ID, target.Name)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "resize instance %s", instance.ID)
+	}
+
+	resized := instance
+	err = waitFor(ctx, civoProvider, "instance "+instance.ID+" to be resized", func() (bool, error) {
+		resized, err = retryValue(ctx, civoProvider.Log, "get instance", func() (*civogo.Instance, error) {
+			return civoProvider.Client.GetInstance(instance.ID)
+		})
+		if err != nil {
+			return false, err
+		} else if IsFailed(resized) {
+			return false, InstanceFailedError(resized)
+		}
+
+		return resized.Size == target.Name && (resized.Status == StatusShutoff || resized.Status == StatusActive), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = saveInstanceSize(civoProvider, instance.ID, target.Name)
+	if err != nil {
+		return err
+	}
+
+	if resized.Status == StatusShutoff {
+		err = retry(ctx, civoProvider.Log, "start instance", func() error {
+			_, err := civoProvider.Client.StartInstance(instance.ID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = WaitForInstance(ctx, civoProvider.Client, instance.ID, waitOptions(civoProvider), civoProvider.Log)
+	return err
+}
+
+// checkResize rejects downgrades, civo can only grow an instance.
+func checkResize(current, target *civogo.InstanceSize) error {
+	smaller := []string{}
+	if target.CPUCores < current.CPUCores {
+		smaller = append(smaller, fmt.Sprintf("%d instead of %d cpu cores", target.CPUCores, current.CPUCores))
+	}
+	if target.RAMMegabytes < current.RAMMegabytes {
+		smaller = append(smaller, fmt.Sprintf("%dMB instead of %dMB ram", target.RAMMegabytes, current.RAMMegabytes))
+	}
+	if target.DiskGigabytes < current.DiskGigabytes {
+		smaller = append(smaller, fmt.Sprintf("%dGB instead of %dGB disk", target.DiskGigabytes, current.DiskGigabytes))
+	}
+
+	if len(smaller) > 0 {
+		return fmt.Errorf(
+			"can't resize from %s to %s, it has %s and civo doesn't allow downgrading an instance, delete and recreate the machine with CIVO_INSTANCE_TYPE=%s instead",
+			current.Name,
+			target.Name,
+			strings.Join(smaller, ", "),
+			target.Name,
+		)
+	}
+
+	return nil
+}
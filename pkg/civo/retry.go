@@ -0,0 +1,230 @@
+package civo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/log"
+)
+
+// RetryOptions configure how failed civo api calls are retried.
+type RetryOptions struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryOptions retry a call for about a minute before giving up.
+var DefaultRetryOptions = RetryOptions{
+	Attempts:  6,
+	BaseDelay: time.Second,
+	MaxDelay:  30 * time.Second,
+}
+
+var (
+	// decodeError only keeps the status code in the message of errors it
+	// doesn't know
+	statusCodePattern = regexp.MustCompile(`code: (\d{3})\b`)
+
+	retryRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// retry calls the idempotent fn until it succeeds, fails with an error that
+// isn't transient or the attempts are used up.
+func retry(ctx context.Context, logs log.Logger, what string, fn func() error) error {
+	_, err := retryValue(ctx, logs, what, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+
+	return err
+}
+
+// retryValue is retry for calls that return a value.
+func retryValue[T any](ctx context.Context, logs log.Logger, what string, fn func() (T, error)) (T, error) {
+	options := DefaultRetryOptions
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+
+		value, err := fn()
+		if err == nil || !IsRetryable(err) || attempt >= options.Attempts {
+			return value, err
+		}
+
+		delay := retryDelay(attempt, options)
+		logs.Debugf("Retrying %s in %s after attempt %d failed: %v", what, delay.Round(time.Millisecond), attempt, err)
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return value, &gaveUpError{err: err, reason: "giving up on " + what, cause: sleepErr}
+		}
+	}
+}
+
+// createOnce runs a create call that isn't idempotent. A transient error
+// doesn't tell if the resource was created, so before trying again find is
+// used to adopt a resource the failed attempt created anyway.
+func createOnce[T any](ctx context.Context, logs log.Logger, what string, create func() (T, error), find func() (T, bool, error)) (T, error) {
+	options := DefaultRetryOptions
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+
+		value, err := create()
+		if err == nil || !IsRetryable(err) || attempt >= options.Attempts {
+			return value, err
+		}
+
+		delay := retryDelay(attempt, options)
+		logs.Debugf("Checking if %s exists before retrying in %s, attempt %d failed: %v", what, delay.Round(time.Millisecond), attempt, err)
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			var zero T
+			return zero, &gaveUpError{err: err, reason: "giving up on " + what, cause: sleepErr}
+		}
+
+		existing, found, findErr := findCreated(ctx, logs, what, find)
+		if findErr != nil {
+			var zero T
+			return zero, &gaveUpError{err: err, reason: "checking if " + what + " was created anyway", cause: findErr}
+		} else if found {
+			logs.Debugf("Found %s created by the failed attempt", what)
+			return existing, nil
+		}
+	}
+}
+
+// gaveUpError is returned when retrying a failed call was given up, e.g.
+// because the context was cancelled. It unwraps to the error of the call and
+// also matches the cause, so errors.Is(err, context.Canceled) holds.
+type gaveUpError struct {
+	err    error
+	reason string
+	cause  error
+}
+
+func (e *gaveUpError) Error() string {
+	return fmt.Sprintf("%v, %s: %v", e.err, e.reason, e.cause)
+}
+
+func (e *gaveUpError) Unwrap() error {
+	return e.err
+}
+
+func (e *gaveUpError) Is(target error) bool {
+	return errors.Is(e.cause, target)
+}
+
+// findCreated retries find, which looks up a resource after a failed create.
+func findCreated[T any](ctx context.Context, logs log.Logger, what string, find func() (T, bool, error)) (T, bool, error) {
+	type result struct {
+		value T
+		found bool
+	}
+
+	r, err := retryValue(ctx, logs, "find "+what, func() (result, error) {
+		value, found, err := find()
+		return result{value: value, found: found}, err
+	})
+
+	return r.value, r.found, err
+}
+
+// IsRetryable returns if the error is a transient network, rate limit or
+// server error, after which the call may succeed.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, civogo.TimeoutError) || errors.Is(err, civogo.InternalServerError) {
+		return true
+	}
+
+	if code := statusCode(err); code != 0 {
+		return code == 429 || code >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsNotFound returns if the error says the resource doesn't exist.
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, notFound := range []error{
+		ErrInstanceNotFound,
+		civogo.ZeroMatchesError,
+		civogo.DatabaseInstanceNotFoundError,
+		civogo.DatabaseVolumeNotFoundError,
+		civogo.DatabaseFirewallNotFoundError,
+		civogo.DatabaseSSHKeyNotFoundError,
+		civogo.DatabaseNetworkNotFoundError,
+	} {
+		if errors.Is(err, notFound) {
+			return true
+		}
+	}
+
+	return statusCode(err) == 404
+}
+
+// statusCode returns the http status code of the failed call, or 0 if it's
+// unknown.
+func statusCode(err error) int {
+	var httpErr civogo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	match := statusCodePattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+
+	code, _ := strconv.Atoi(match[1])
+	return code
+}
+
+// retryDelay backs off exponentially with jitter. A Retry-After header of a
+// rate limited call can't be honored, civogo only keeps the response body in
+// the error and replaces the transport of its http client on every request.
+func retryDelay(attempt int, options RetryOptions) time.Duration {
+	delay := options.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > options.MaxDelay {
+		delay = options.MaxDelay
+	}
+
+	// equal jitter, so concurrent clients don't retry in lockstep
+	half := delay / 2
+	return half + time.Duration(retryRand.Int63n(int64(half)+1))
+}
+
+// sleep waits for the duration or until the context is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
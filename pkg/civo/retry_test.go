@@ -0,0 +1,96 @@
+package civo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/civo/civogo"
+)
+
+// rateLimitedClient returns a client whose api answers the first limited
+// requests with 429 Too Many Requests and then returns the quota.
+func rateLimitedClient(t *testing.T, limited int) (*civogo.Client, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests <= limited {
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			_, _ = rw.Write([]byte(`{"code": "rate_limited", "reason": "too many requests"}`))
+			return
+		}
+
+		_, _ = rw.Write([]byte(`{"instance_count_limit": 10}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := civogo.NewClientForTestingWithServer(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultRetryOptions
+	DefaultRetryOptions = RetryOptions{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	t.Cleanup(func() { DefaultRetryOptions = options })
+
+	return client, &requests
+}
+
+func TestRetryRateLimited(t *testing.T) {
+	client, requests := rateLimitedClient(t, 2)
+
+	quota, err := retryValue(context.Background(), testLog, "get quota", client.GetQuota)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *requests != 3 || quota.InstanceCountLimit != 10 {
+		t.Errorf("expected the quota after 2 rate limited requests, got %+v after %d requests", quota, *requests)
+	}
+}
+
+func TestRetryRateLimitedGivesUp(t *testing.T) {
+	client, requests := rateLimitedClient(t, 5)
+
+	_, err := retryValue(context.Background(), testLog, "get quota", client.GetQuota)
+	if statusCode(err) != http.StatusTooManyRequests || !IsRetryable(err) {
+		t.Errorf("expected the rate limit error, got %v", err)
+	}
+	if *requests != 3 {
+		t.Errorf("expected 3 attempts, got %d", *requests)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	apiErr := civogo.HTTPError{Code: 503, Status: "503 Service Unavailable", Reason: "unavailable"}
+
+	err := retry(ctx, testLog, "get instance", func() error {
+		cancel()
+		return apiErr
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the error to match context.Canceled, got %v", err)
+	}
+	if statusCode(err) != 503 {
+		t.Errorf("expected the error to keep the status code of the call, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	_, err = createOnce(ctx, testLog, "instance",
+		func() (string, error) {
+			cancel()
+			return "", apiErr
+		},
+		func() (string, bool, error) {
+			return "", false, nil
+		},
+	)
+	if !errors.Is(err, context.Canceled) || statusCode(err) != 503 {
+		t.Errorf("expected the error to match context.Canceled and keep the status code, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package civo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/pkg/errors"
+)
+
+// MaxScriptSize is the largest startup script civo accepts for an instance.
+const MaxScriptSize = 64 * 1024
+
+// DataDiskMountPath is where the extra data volume is mounted.
+const DataDiskMountPath = "/var/lib/docker"
+
+// StartupStatusFile is where the startup script records its exit code once it
+// finished.
+const StartupStatusFile = "/var/lib/devpod-startup-status"
+
+// ScriptData holds the variables of the startup script. A user supplied
+// CIVO_INIT_SCRIPT gets them as DEVPOD_* environment variables.
+type ScriptData struct {
+	MachineID   string
+	WorkspaceID string
+	Region      string
+	InitialUser string
+	AgentPath   string
+	AgentDir    string
+
+	DataDisk          bool
+	DataDiskMountPath string
+
+	// PersistAgentDir keeps the agent directory on the data disk
+	PersistAgentDir bool
+
+	UserScript string
+}
+
+var startupScript = template.Must(template.New("startup").Parse(`#!/bin/sh
+set -e
+
+# record the exit code, the provider waits for it before DevPod uses the machine
+trap 'echo $? > ` + StartupStatusFile + `' EXIT
+
+# only allow key based ssh logins
+if [ -d /etc/ssh/sshd_config.d ]; then
+  echo "PasswordAuthentication no" > /etc/ssh/sshd_config.d/00-devpod.conf
+fi
+sed -i -E 's/^#?PasswordAuthentication .*/PasswordAuthentication no/' /etc/ssh/sshd_config
+systemctl reload ssh 2>/dev/null || systemctl reload sshd 2>/dev/null || true
+{{ if .DataDisk }}
+# wait for the data volume to be attached, it's the only disk without a mounted partition
+DEVICE=""
+for i in $(seq 1 120); do
+  for disk in $(lsblk -dpno NAME,TYPE | awk '$2 == "disk" { print $1 }'); do
+    if [ -z "$(lsblk -no MOUNTPOINT "$disk" | tr -d '[:space:]')" ]; then
+      DEVICE="$disk"
+      break
+    fi
+  done
+  if [ -n "$DEVICE" ]; then
+    break
+  fi
+  sleep 5
+done
+
+if [ -z "$DEVICE" ]; then
+  echo "data volume was not attached" >&2
+  exit 1
+fi
+
+if ! blkid "$DEVICE" >/dev/null 2>&1; then
+  mkfs.ext4 -q "$DEVICE"
+fi
+
+mkdir -p {{ .DataDiskMountPath }}
+UUID=$(blkid -s UUID -o value "$DEVICE")
+if ! grep -q "$UUID" /etc/fstab; then
+  echo "UUID=$UUID {{ .DataDiskMountPath }} ext4 defaults,nofail 0 2" >> /etc/fstab
+fi
+mount -a
+{{ if .PersistAgentDir }}
+# keep the agent data on the volume, so the workspace survives recreating the instance
+mkdir -p {{ .DataDiskMountPath }}/.devpod-agent {{ .AgentDir }}
+if ! grep -q " {{ .AgentDir }} none bind" /etc/fstab; then
+  echo "{{ .DataDiskMountPath }}/.devpod-agent {{ .AgentDir }} none bind,nofail 0 0" >> /etc/fstab
+fi
+mount -a
+{{ end -}}
+{{ end }}
+# install docker so the agent doesn't have to on first connect
+if ! command -v docker >/dev/null 2>&1; then
+  if ! command -v curl >/dev/null 2>&1; then
+    apt-get update -qq && apt-get install -y -qq curl
+  fi
+  curl -fsSL https://get.docker.com | sh
+fi
+usermod -aG docker {{ .InitialUser }} || true
+
+# prepare the agent directory
+mkdir -p {{ .AgentDir }}
+chown {{ .InitialUser }} {{ .AgentDir }}
+{{ if .UserScript }}
+cat > /usr/local/bin/devpod-init-script <<'DEVPOD_INIT_SCRIPT'
+{{ .UserScript }}
+DEVPOD_INIT_SCRIPT
+chmod 0700 /usr/local/bin/devpod-init-script
+DEVPOD_MACHINE_ID='{{ .MachineID }}' \
+DEVPOD_WORKSPACE_ID='{{ .WorkspaceID }}' \
+DEVPOD_REGION='{{ .Region }}' \
+DEVPOD_INITIAL_USER='{{ .InitialUser }}' \
+DEVPOD_AGENT_PATH='{{ .AgentPath }}' \
+DEVPOD_AGENT_DIR='{{ .AgentDir }}' \
+DEVPOD_DATA_DISK='{{ if .DataDisk }}{{ .DataDiskMountPath }}{{ end }}' \
+  /usr/local/bin/devpod-init-script
+{{ end -}}
+`))
+
+// RenderScript renders the startup script for a new instance. The user
+// supplied init script is inserted as is, so it can use {{ and }} like any
+// shell script.
+func RenderScript(data ScriptData) (string, error) {
+	if data.AgentDir == "" && data.AgentPath != "" {
+		data.AgentDir = path.Dir(data.AgentPath)
+	}
+
+	data.UserScript = strings.TrimRight(data.UserScript, "\n")
+
+	buf := &bytes.Buffer{}
+	err := startupScript.Execute(buf, data)
+	if err != nil {
+		return "", errors.Wrap(err, "render startup script")
+	}
+
+	if buf.Len() > MaxScriptSize {
+		return "", fmt.Errorf(
+			"startup script is %d bytes, civo allows at most %d bytes, please shorten CIVO_INIT_SCRIPT",
+			buf.Len(),
+			MaxScriptSize,
+		)
+	}
+
+	return buf.String(), nil
+}
+
+// startupStatusCommand prints the exit code of the startup script once it
+// finished. Instances created before the script recorded it print "done" once
+// cloud-init finished.
+const startupStatusCommand = "cat " + StartupStatusFile + " 2>/dev/null || " +
+	"{ sudo -n cloud-init status 2>/dev/null | grep -Eq 'status: (done|error|disabled)' && echo done; } || true"
+
+// readStartupStatus runs startupStatusCommand on the instance, tests replace
+// it.
+var readStartupStatus = readStartupStatusSSH
+
+// waitForStartupScript waits until the startup script of the instance
+// finished. It mounts the data volume and installs docker, which DevPod relies
+// on as soon as the machine is created.
+func waitForStartupScript(ctx context.Context, civoProvider *CivoProvider, instance *civogo.Instance) error {
+	address := InstanceAddress(instance, !civoProvider.Config.PublicIP)
+	status := ""
+	var lastErr error
+	err := waitFor(ctx, civoProvider, "the startup script of instance "+instance.ID, func() (bool, error) {
+		status, lastErr = readStartupStatus(ctx, civoProvider, address)
+		if lastErr != nil {
+			// sshd is restarted while the script runs
+			civoProvider.Log.Debugf("Error reading the startup script status of instance %s: %v", instance.ID, lastErr)
+			return false, nil
+		}
+
+		return status != "", nil
+	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("%w: %v", err, lastErr)
+	} else if err != nil {
+		return err
+	}
+
+	if status != "0" && status != "done" {
+		return fmt.Errorf("startup script of instance %s failed with exit code %s, see /var/log/cloud-init-output.log on the instance", instance.ID, status)
+	}
+
+	return nil
+}
+
+func readStartupStatusSSH(ctx context.Context, civoProvider *CivoProvider, address string) (string, error) {
+	privateKey, err := ssh.GetPrivateKeyRawBase(civoProvider.Config.MachineFolder)
+	if err != nil {
+		return "", errors.Wrap(err, "load private key")
+	}
+
+	sshClient, err := ssh.NewSSHClient(InitialUser, net.JoinHostPort(address, sshPort), privateKey)
+	if err != nil {
+		return "", errors.Wrap(err, "connect")
+	}
+	defer sshClient.Close()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err = ssh.Run(ctx, sshClient, startupStatusCommand, nil, stdout, stderr)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
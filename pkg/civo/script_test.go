@@ -0,0 +1,157 @@
+package civo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/civo/civogo"
+)
+
+func testScriptData() ScriptData {
+	return ScriptData{
+		MachineID:         "devpod-test",
+		WorkspaceID:       "workspace",
+		Region:            "LON1",
+		InitialUser:       "civo",
+		AgentPath:         "/var/lib/toolbox/devpod",
+		DataDiskMountPath: DataDiskMountPath,
+	}
+}
+
+func TestRenderScript(t *testing.T) {
+	script, err := RenderScript(testScriptData())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"#!/bin/sh\n",
+		"trap 'echo $? > /var/lib/devpod-startup-status' EXIT\n",
+		"PasswordAuthentication no",
+		"usermod -aG docker civo",
+		"mkdir -p /var/lib/toolbox\n",
+		"chown civo /var/lib/toolbox\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(script, e) {
+			t.Errorf("expected the script to contain %q, got:\n%s", e, script)
+		}
+	}
+
+	unexpected := []string{"mkfs.ext4", "devpod-init-script"}
+	for _, u := range unexpected {
+		if strings.Contains(script, u) {
+			t.Errorf("expected the script not to contain %q, got:\n%s", u, script)
+		}
+	}
+}
+
+func TestRenderScriptDataDisk(t *testing.T) {
+	data := testScriptData()
+	data.DataDisk = true
+
+	script, err := RenderScript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(script, "ext4 defaults,nofail 0 2\" >> /etc/fstab") || !strings.Contains(script, "mkdir -p /var/lib/docker\n") {
+		t.Errorf("expected the data volume to be mounted at /var/lib/docker, got:\n%s", script)
+	}
+	if strings.Contains(script, ".devpod-agent") {
+		t.Errorf("expected the agent directory not to be persisted, got:\n%s", script)
+	}
+
+	data.PersistAgentDir = true
+	script, err = RenderScript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(script, "/var/lib/docker/.devpod-agent /var/lib/toolbox none bind,nofail 0 0") {
+		t.Errorf("expected the agent directory to be bind mounted from the data volume, got:\n%s", script)
+	}
+}
+
+func TestRenderScriptUserScript(t *testing.T) {
+	data := testScriptData()
+	data.DataDisk = true
+	data.UserScript = "#!/bin/bash\necho \"${DEVPOD_MACHINE_ID}\" > /tmp/machine\ndocker inspect -f '{{ .State.Status }}' app\n\n"
+
+	script, err := RenderScript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "cat > /usr/local/bin/devpod-init-script <<'DEVPOD_INIT_SCRIPT'\n" +
+		"#!/bin/bash\necho \"${DEVPOD_MACHINE_ID}\" > /tmp/machine\ndocker inspect -f '{{ .State.Status }}' app\n" +
+		"DEVPOD_INIT_SCRIPT\n" +
+		"chmod 0700 /usr/local/bin/devpod-init-script\n" +
+		"DEVPOD_MACHINE_ID='devpod-test' \\\n" +
+		"DEVPOD_WORKSPACE_ID='workspace' \\\n" +
+		"DEVPOD_REGION='LON1' \\\n" +
+		"DEVPOD_INITIAL_USER='civo' \\\n" +
+		"DEVPOD_AGENT_PATH='/var/lib/toolbox/devpod' \\\n" +
+		"DEVPOD_AGENT_DIR='/var/lib/toolbox' \\\n" +
+		"DEVPOD_DATA_DISK='/var/lib/docker' \\\n" +
+		"  /usr/local/bin/devpod-init-script\n"
+	if !strings.HasSuffix(script, expected) {
+		t.Errorf("expected the script to end with the init script, got:\n%s", script)
+	}
+}
+
+func TestRenderScriptTooLarge(t *testing.T) {
+	data := testScriptData()
+	data.UserScript = strings.Repeat("#", MaxScriptSize)
+	_, err := RenderScript(data)
+	if err == nil || !strings.Contains(err.Error(), "please shorten CIVO_INIT_SCRIPT") {
+		t.Errorf("expected the script to be too large, got %v", err)
+	}
+}
+
+func TestWaitForStartupScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		expected string
+	}{
+		{name: "succeeded", statuses: []string{"", "", "0"}},
+		{name: "created before the status was recorded", statuses: []string{"done"}},
+		{name: "failed", statuses: []string{"", "1"}, expected: "startup script of instance instance-1 failed with exit code 1"},
+		{name: "still running", statuses: []string{""}, expected: "timed out"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := readStartupStatus
+			t.Cleanup(func() { readStartupStatus = status })
+
+			reads := 0
+			readStartupStatus = func(ctx context.Context, civoProvider *CivoProvider, address string) (string, error) {
+				if address != "192.0.2.10" {
+					t.Errorf("expected the status to be read from 192.0.2.10, got %s", address)
+				}
+
+				reads++
+				if reads > len(test.statuses) {
+					return test.statuses[len(test.statuses)-1], nil
+				}
+
+				return test.statuses[reads-1], nil
+			}
+
+			civoProvider := newTestProvider(nil, t.TempDir())
+			civoProvider.Config.CreateTimeout = 50 * time.Millisecond
+			civoProvider.Config.PollInterval = time.Millisecond
+
+			err := waitForStartupScript(context.Background(), civoProvider, &civogo.Instance{ID: "instance-1", PublicIP: "192.0.2.10"})
+			if test.expected == "" && err != nil {
+				t.Fatal(err)
+			} else if test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)) {
+				t.Fatalf("expected an error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package civo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const machineStateFile = "civo.json"
+
+// MachineState is persisted in the machine folder on create, so later commands
+// can look the instance up by its exact ID instead of searching by hostname.
+type MachineState struct {
+	InstanceID string `json:"instanceID,omitempty"`
+	Region     string `json:"region,omitempty"`
+
+	// Size is the instance size, which differs from CIVO_INSTANCE_TYPE after
+	// the machine was resized.
+	Size string `json:"size,omitempty"`
+
+	// ReservedIPID is the reserved IP allocated for the machine. Only that one
+	// is released with the machine, a reserved IP that existed before is kept.
+	ReservedIPID string `json:"reservedIPID,omitempty"`
+}
+
+// LoadMachineState reads the machine state, returning nil if there is none.
+func LoadMachineState(machineFolder string) (*MachineState, error) {
+	if machineFolder == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(machineFolder, machineStateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "read machine state")
+	}
+
+	state := &MachineState{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse machine state")
+	}
+
+	return state, nil
+}
+
+// machineSize returns the instance size of the machine, which is the one
+// recorded by a resize if there is one.
+func machineSize(civoProvider *CivoProvider) (string, error) {
+	state, err := LoadMachineState(civoProvider.Config.MachineFolder)
+	if err != nil {
+		return "", err
+	}
+
+	if state != nil && state.Size != "" {
+		return state.Size, nil
+	}
+
+	return civoProvider.Config.MachineType, nil
+}
+
+func SaveMachineState(machineFolder string, state *MachineState) error {
+	if machineFolder == "" {
+		return nil
+	}
+
+	err := os.MkdirAll(machineFolder, 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(machineFolder, machineStateFile), data, 0600)
+	if err != nil {
+		return errors.Wrap(err, "write machine state")
+	}
+
+	return nil
+}
+
+// saveInstanceSize records the instance and its size, so the machine keeps the
+// size when its instance is recreated.
+func saveInstanceSize(civoProvider *CivoProvider, instanceID, size string) error {
+	return updateMachineState(civoProvider.Config.MachineFolder, func(state *MachineState) {
+		state.InstanceID = instanceID
+		state.Region = civoProvider.Config.Region
+		state.Size = size
+	})
+}
+
+// updateMachineState changes the recorded machine state, keeping the fields
+// update doesn't set.
+func updateMachineState(machineFolder string, update func(state *MachineState)) error {
+	state, err := LoadMachineState(machineFolder)
+	if err != nil {
+		return err
+	} else if state == nil {
+		state = &MachineState{}
+	}
+
+	update(state)
+	return SaveMachineState(machineFolder, state)
+}
+
+func DeleteMachineState(machineFolder string) error {
+	if machineFolder == "" {
+		return nil
+	}
+
+	err := os.Remove(filepath.Join(machineFolder, machineStateFile))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "delete machine state")
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package civo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/client"
+)
+
+// Instance states reported by the civo api.
+const (
+	StatusActive    = "ACTIVE"
+	StatusBuilding  = "BUILDING"
+	StatusStarting  = "STARTING"
+	StatusStopping  = "STOPPING"
+	StatusShutoff   = "SHUTOFF"
+	StatusRebooting = "REBOOTING"
+	StatusMigrating = "MIGRATING"
+	StatusDeleting  = "DELETING"
+	StatusError     = "ERROR"
+	StatusFailed    = "FAILED"
+)
+
+// InstanceStatus maps the state of the instance to a DevPod status. An instance
+// in a failed state returns an error with the reason, as DevPod has no status
+// for it and would otherwise wait for it forever.
+func InstanceStatus(instance *civogo.Instance) (client.Status, error) {
+	switch strings.ToUpper(instance.Status) {
+	case StatusActive:
+		return client.StatusRunning, nil
+	case StatusShutoff:
+		return client.StatusStopped, nil
+	case StatusBuilding, StatusStarting, StatusStopping, StatusRebooting, StatusMigrating, StatusDeleting:
+		return client.StatusBusy, nil
+	case StatusError, StatusFailed:
+		return client.StatusNotFound, InstanceFailedError(instance)
+	default:
+		// states civo adds later are most likely transitions
+		return client.StatusBusy, nil
+	}
+}
+
+// IsFailed returns if the instance is in a state it doesn't recover from.
+func IsFailed(instance *civogo.Instance) bool {
+	status := strings.ToUpper(instance.Status)
+	return status == StatusError || status == StatusFailed
+}
+
+// InstanceFailedError describes why the instance is unusable.
+func InstanceFailedError(instance *civogo.Instance) error {
+	return fmt.Errorf(
+		"instance %s (%s) is in state %s, civo couldn't build or run it. Delete the machine and create it again, or contact civo support if it keeps failing",
+		instance.Hostname,
+		instance.ID,
+		instance.Status,
+	)
+}
+
+// StateHibernated is the state of a hibernated machine, which has no instance.
+const StateHibernated = "HIBERNATED"
+
+// InstanceDetails describes the instance of a machine for machine readable
+// output.
+type InstanceDetails struct {
+	Status     client.Status `json:"status"`
+	Error      string        `json:"error,omitempty"`
+	ID         string        `json:"id,omitempty"`
+	Hostname   string        `json:"hostname,omitempty"`
+	State      string        `json:"state,omitempty"`
+	Region     string        `json:"region,omitempty"`
+	Size       string        `json:"size,omitempty"`
+	CPUCores   int           `json:"cpuCores,omitempty"`
+	RAMMB      int           `json:"ramMB,omitempty"`
+	DiskGB     int           `json:"diskGB,omitempty"`
+	PublicIP   string        `json:"publicIP,omitempty"`
+	PrivateIP  string        `json:"privateIP,omitempty"`
+	IPv6       string        `json:"ipv6,omitempty"`
+	ReservedIP string        `json:"reservedIP,omitempty"`
+	FirewallID string        `json:"firewallID,omitempty"`
+	NetworkID  string        `json:"networkID,omitempty"`
+	Tags       []string      `json:"tags,omitempty"`
+	CreatedAt  *time.Time    `json:"createdAt,omitempty"`
+}
+
+// NewInstanceDetails returns the details of the instance, a nil instance is
+// reported as not found.
+func NewInstanceDetails(instance *civogo.Instance) *InstanceDetails {
+	if instance == nil {
+		return &InstanceDetails{Status: client.StatusNotFound}
+	}
+
+	details := &InstanceDetails{
+		ID:         instance.ID,
+		Hostname:   instance.Hostname,
+		State:      instance.Status,
+		Region:     instance.Region,
+		Size:       instance.Size,
+		CPUCores:   instance.CPUCores,
+		RAMMB:      instance.RAMMegabytes,
+		DiskGB:     instance.DiskGigabytes,
+		PublicIP:   instance.PublicIP,
+		PrivateIP:  instance.PrivateIP,
+		IPv6:       instance.IPv6,
+		ReservedIP: instance.ReservedIP,
+		FirewallID: instance.FirewallID,
+		NetworkID:  instance.NetworkID,
+		Tags:       instance.Tags,
+	}
+
+	if !instance.CreatedAt.IsZero() {
+		details.CreatedAt = &instance.CreatedAt
+	}
+
+	status, err := InstanceStatus(instance)
+	details.Status = status
+	if err != nil {
+		details.Error = err.Error()
+	}
+
+	return details
+}
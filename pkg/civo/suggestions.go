@@ -0,0 +1,237 @@
+package civo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/loft-sh/devpod/pkg/provider"
+	"github.com/pkg/errors"
+)
+
+// OptionValue is an available option value with a human readable label.
+type OptionValue struct {
+	Value       string `json:"value"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// Suggestions are the values available to the account per provider option.
+type Suggestions struct {
+	Options map[string][]OptionValue `json:"options"`
+}
+
+// ProviderOptions are options in the format of the options in provider.yaml.
+type ProviderOptions struct {
+	Options map[string]*provider.ProviderOption `json:"options"`
+}
+
+// ProviderOptions returns the values as suggestions of the provider options.
+// They aren't an enum, so values that became available after the suggestions
+// were listed can still be used.
+func (s *Suggestions) ProviderOptions() *ProviderOptions {
+	providerOptions := &ProviderOptions{Options: map[string]*provider.ProviderOption{}}
+	for name, values := range s.Options {
+		option := &provider.ProviderOption{}
+		for _, value := range values {
+			option.Suggestions = append(option.Suggestions, value.Value)
+		}
+
+		providerOptions.Options[name] = option
+	}
+
+	return providerOptions
+}
+
+type suggestionsCache struct {
+	CreatedAt   time.Time    `json:"createdAt"`
+	Suggestions *Suggestions `json:"suggestions"`
+}
+
+// GetSuggestions returns the regions, instance sizes and disk images available
+// to the account, cached locally for the given ttl.
+func GetSuggestions(ctx context.Context, client *civogo.Client, ttl time.Duration, logs log.Logger) (*Suggestions, error) {
+	cacheFile := suggestionsCacheFile(client)
+	if ttl > 0 && cacheFile != "" {
+		suggestions := loadSuggestionsCache(cacheFile, ttl)
+		if suggestions != nil {
+			return suggestions, nil
+		}
+	}
+
+	suggestions, err := listSuggestions(ctx, client, logs)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheFile != "" {
+		err = saveSuggestionsCache(cacheFile, suggestions)
+		if err != nil {
+			logs.Debugf("Error caching option suggestions: %v", err)
+		}
+	}
+
+	return suggestions, nil
+}
+
+func listSuggestions(ctx context.Context, client *civogo.Client, logs log.Logger) (*Suggestions, error) {
+	regions, err := retryValue(ctx, logs, "list regions", client.ListRegions)
+	if err != nil {
+		return nil, errors.Wrap(err, "list regions")
+	}
+
+	sizes, err := retryValue(ctx, logs, "list instance sizes", client.ListInstanceSizes)
+	if err != nil {
+		return nil, errors.Wrap(err, "list instance sizes")
+	}
+
+	images, err := retryValue(ctx, logs, "list disk images", client.ListDiskImages)
+	if err != nil {
+		return nil, errors.Wrap(err, "list disk images")
+	}
+
+	suggestions := &Suggestions{
+		Options: map[string][]OptionValue{},
+	}
+
+	for _, region := range regions {
+		if !region.Features.Iaas || region.OutOfCapacity {
+			continue
+		}
+
+		suggestions.add(options.CIVO_REGION, region.Code, regionLabel(region))
+	}
+
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].CPUCores != sizes[j].CPUCores {
+			return sizes[i].CPUCores < sizes[j].CPUCores
+		}
+
+		return sizes[i].RAMMegabytes < sizes[j].RAMMegabytes
+	})
+	for _, size := range sizes {
+		if !isInstanceSize(size) {
+			continue
+		}
+
+		suggestions.add(options.CIVO_INSTANCE_TYPE, size.Name, sizeLabel(size))
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Name < images[j].Name
+	})
+	for _, image := range images {
+		if image.State != "" && image.State != "available" {
+			continue
+		}
+
+		suggestions.add(options.CIVO_DISK_IMAGE, image.Name, imageLabel(image))
+	}
+
+	return suggestions, nil
+}
+
+func (s *Suggestions) add(option, value, displayName string) {
+	s.Options[option] = append(s.Options[option], OptionValue{Value: value, DisplayName: displayName})
+}
+
+// isInstanceSize filters out the kubernetes and database sizes.
+func isInstanceSize(size civogo.InstanceSize) bool {
+	if !size.Selectable {
+		return false
+	}
+
+	for _, kind := range []string{".kube.", ".k3s.", ".db.", ".gpu."} {
+		if strings.Contains(size.Name, kind) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func regionLabel(region civogo.Region) string {
+	if region.CountryName == "" {
+		return region.Code
+	}
+
+	return fmt.Sprintf("%s (%s)", region.Code, region.CountryName)
+}
+
+func sizeLabel(size civogo.InstanceSize) string {
+	label := fmt.Sprintf("%s - %d CPU, %dGB RAM, %dGB disk", size.Name, size.CPUCores, size.RAMMegabytes/1024, size.DiskGigabytes)
+	switch price, source := MonthlyPrice(size.Name); source {
+	case PriceSourceTable:
+		label += fmt.Sprintf(", $%.2f/month (built-in list price)", price)
+	case PriceSourceEnv:
+		label += fmt.Sprintf(", $%.2f/month (%s)", price, PricesEnv)
+	default:
+		label += ", price unknown"
+	}
+
+	return label
+}
+
+func imageLabel(image civogo.DiskImage) string {
+	if image.Distribution == "" {
+		return image.Name
+	}
+
+	distribution := strings.ToUpper(image.Distribution[:1]) + image.Distribution[1:]
+	return fmt.Sprintf("%s %s (%s)", distribution, image.Version, image.Name)
+}
+
+// suggestionsCacheFile returns a cache file per api key and region, or an
+// empty string if there is no cache directory.
+func suggestionsCacheFile(client *civogo.Client) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(client.APIKey))
+	name := fmt.Sprintf("options-%s-%s.json", strings.ToLower(client.Region), hex.EncodeToString(hash[:])[:12])
+
+	return filepath.Join(cacheDir, "devpod-provider-civo", name)
+}
+
+func loadSuggestionsCache(cacheFile string, ttl time.Duration) *Suggestions {
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		return nil
+	}
+
+	cache := &suggestionsCache{}
+	err = json.Unmarshal(data, cache)
+	if err != nil || cache.Suggestions == nil || time.Since(cache.CreatedAt) > ttl {
+		return nil
+	}
+
+	return cache.Suggestions
+}
+
+func saveSuggestionsCache(cacheFile string, suggestions *Suggestions) error {
+	err := os.MkdirAll(filepath.Dir(cacheFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&suggestionsCache{
+		CreatedAt:   time.Now(),
+		Suggestions: suggestions,
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cacheFile, data, 0600)
+}
@@ -0,0 +1,114 @@
+package civo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/version"
+)
+
+// DevpodTag is set on every instance created by the provider.
+const DevpodTag = "devpod"
+
+const (
+	MachineTagPrefix = "devpod-machine:"
+	OwnerTagPrefix   = "devpod-owner:"
+	VersionTagPrefix = "devpod-version:"
+)
+
+func MachineTag(machineID string) string {
+	return MachineTagPrefix + machineID
+}
+
+func OwnerTag(owner string) string {
+	return OwnerTagPrefix + sanitizeTag(owner)
+}
+
+// instanceTags returns the tags for a new instance of the machine.
+func instanceTags(civoProvider *CivoProvider) []string {
+	tags := []string{
+		DevpodTag,
+		MachineTag(civoProvider.Config.MachineID),
+	}
+
+	if version.Version != "" {
+		tags = append(tags, VersionTagPrefix+sanitizeTag(version.Version))
+	}
+
+	if civoProvider.Config.Owner != "" {
+		tags = append(tags, OwnerTag(civoProvider.Config.Owner))
+	}
+
+	for _, tag := range civoProvider.Config.Tags {
+		if !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// instanceNotes describes who created the instance for what.
+func instanceNotes(civoProvider *CivoProvider) string {
+	notes := []string{
+		fmt.Sprintf("Created by DevPod (devpod-provider-civo %s)", version.Version),
+		"Machine: " + civoProvider.Config.MachineID,
+	}
+
+	if civoProvider.Config.Owner != "" {
+		notes = append(notes, "Owner: "+civoProvider.Config.Owner)
+	}
+
+	if source := workspaceSource(); source != "" {
+		notes = append(notes, "Source: "+source)
+	}
+
+	return strings.Join(notes, "\n")
+}
+
+func workspaceSource() string {
+	if repository := os.Getenv("WORKSPACE_GIT_REPOSITORY"); repository != "" {
+		if branch := os.Getenv("WORKSPACE_GIT_BRANCH"); branch != "" {
+			return repository + "@" + branch
+		}
+
+		return repository
+	} else if image := os.Getenv("WORKSPACE_IMAGE"); image != "" {
+		return image
+	}
+
+	return os.Getenv("WORKSPACE_LOCAL_FOLDER")
+}
+
+// IsDevpodInstance returns if the instance was created by the provider.
+func IsDevpodInstance(instance *civogo.Instance) bool {
+	return hasTag(instance.Tags, DevpodTag)
+}
+
+// TagValue returns the value of the first tag with the given prefix.
+func TagValue(tags []string, prefix string) string {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, prefix) {
+			return strings.TrimPrefix(tag, prefix)
+		}
+	}
+
+	return ""
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sanitizeTag makes the value usable in the space separated civo tag list.
+func sanitizeTag(value string) string {
+	return strings.Join(strings.Fields(value), "-")
+}
@@ -0,0 +1,187 @@
+package civo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// Validate checks the credentials and options against the civo api and
+// returns a single error describing everything that's wrong.
+func Validate(ctx context.Context, client civogo.Clienter, config *options.Options, logs log.Logger) error {
+	_, err := retryValue(ctx, logs, "get quota", client.GetQuota)
+	if err != nil {
+		return errors.Wrap(err, "authenticate with CIVO_API_KEY")
+	}
+
+	problems := []string{}
+
+	regionProblem, err := validateRegion(ctx, client, logs, config.Region)
+	if err != nil {
+		return err
+	} else if regionProblem != "" {
+		// sizes and images are listed per region, so there's nothing more to check
+		return validationError(append(problems, regionProblem))
+	}
+
+	sizeProblem, err := validateInstanceSize(ctx, client, logs, config.MachineType)
+	if err != nil {
+		return err
+	} else if sizeProblem != "" {
+		problems = append(problems, sizeProblem)
+	}
+
+	_, err = ResolveDiskImage(ctx, client, logs, config.DiskImage, config.Region)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", options.CIVO_DISK_IMAGE, err))
+	}
+
+	if config.Network != "" && !config.CreateNetwork {
+		_, err = retryValue(ctx, logs, "find network", func() (*civogo.Network, error) {
+			return findNetwork(client, config.Network)
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: network %q not found, set %s=true to create it", options.CIVO_NETWORK, config.Network, options.CIVO_NETWORK_CREATE))
+		}
+	}
+
+	if config.Firewall != "" {
+		_, err = retryValue(ctx, logs, "find firewall", func() (*civogo.Firewall, error) {
+			return findFirewall(client, config.Firewall)
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: firewall %q not found", options.CIVO_FIREWALL, config.Firewall))
+		}
+	}
+
+	if len(problems) > 0 {
+		return validationError(problems)
+	}
+
+	return nil
+}
+
+func validateRegion(ctx context.Context, client civogo.Clienter, logs log.Logger, region string) (string, error) {
+	regions, err := retryValue(ctx, logs, "list regions", client.ListRegions)
+	if err != nil {
+		return "", errors.Wrap(err, "list regions")
+	}
+
+	codes := []string{}
+	for _, r := range regions {
+		if strings.EqualFold(r.Code, region) {
+			if r.OutOfCapacity {
+				return fmt.Sprintf("%s: region %s is out of capacity", options.CIVO_REGION, r.Code), nil
+			}
+
+			return "", nil
+		}
+
+		codes = append(codes, r.Code)
+	}
+
+	return notFound(options.CIVO_REGION, "region", region, codes), nil
+}
+
+func validateInstanceSize(ctx context.Context, client civogo.Clienter, logs log.Logger, size string) (string, error) {
+	sizes, err := retryValue(ctx, logs, "list instance sizes", client.ListInstanceSizes)
+	if err != nil {
+		return "", errors.Wrap(err, "list instance sizes")
+	}
+
+	names := []string{}
+	for _, s := range sizes {
+		if !s.Selectable {
+			continue
+		}
+
+		if s.Name == size {
+			return "", nil
+		}
+
+		names = append(names, s.Name)
+	}
+
+	return notFound(options.CIVO_INSTANCE_TYPE, "instance type", size, names), nil
+}
+
+func notFound(option, kind, value string, candidates []string) string {
+	sort.Strings(candidates)
+
+	message := fmt.Sprintf("%s: %s %q is not available", option, kind, value)
+	if suggestion := didYouMean(value, candidates); suggestion != "" {
+		message += fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+
+	return message + " Available: " + strings.Join(candidates, ", ")
+}
+
+func validationError(problems []string) error {
+	return fmt.Errorf("invalid civo provider options:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// didYouMean returns the candidate closest to value, if it is close enough to
+// likely be a typo.
+func didYouMean(value string, candidates []string) string {
+	best := ""
+	bestDistance := -1
+	for _, candidate := range candidates {
+		distance := levenshtein(strings.ToLower(value), strings.ToLower(candidate))
+		if bestDistance == -1 || distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	maxDistance := len(value) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	if bestDistance == -1 || bestDistance > maxDistance {
+		return ""
+	}
+
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
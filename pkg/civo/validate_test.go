@@ -0,0 +1,151 @@
+package civo
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod-provider-civo/pkg/options"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+var validateResponses = map[string]string{
+	"/v2/quota": `{"instance_count_limit": 10, "cpu_core_limit": 10}`,
+	"/v2/regions": `[
+		{"code": "LON1", "name": "London 1"},
+		{"code": "NYC1", "name": "New York 1"},
+		{"code": "FRA1", "name": "Frankfurt 1", "out_of_capacity": true}
+	]`,
+	"/v2/sizes": `[
+		{"id": "1", "name": "g3.small", "cpu_cores": 1, "disk_gb": 25, "selectable": true},
+		{"id": "2", "name": "g3.medium", "cpu_cores": 2, "disk_gb": 50, "selectable": true},
+		{"id": "3", "name": "g3.k3s.small", "cpu_cores": 1, "disk_gb": 40, "selectable": false}
+	]`,
+	"/v2/disk_images": `[
+		{"id": "image-1", "name": "ubuntu-jammy", "distribution": "ubuntu", "version": "22.04"},
+		{"id": "image-2", "name": "debian-11", "distribution": "debian", "version": "11"}
+	]`,
+	"/v2/networks": `[
+		{"id": "network-1", "name": "default", "label": "Default", "default": true},
+		{"id": "network-2", "name": "devpod-network", "label": "devpod-network"}
+	]`,
+	"/v2/firewalls": `[
+		{"id": "firewall-1", "name": "devpod-firewall"}
+	]`,
+}
+
+func validate(t *testing.T, config *options.Options) error {
+	client, server, err := civogo.NewClientForTesting(validateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	return Validate(context.Background(), client, config, log.NewStreamLogger(io.Discard, io.Discard, logrus.ErrorLevel))
+}
+
+func validConfig() *options.Options {
+	return &options.Options{
+		Region:      "LON1",
+		MachineType: "g3.small",
+		DiskImage:   "ubuntu-jammy",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config := validConfig()
+	config.Region = "lon1"
+	config.DiskImage = "debian"
+	config.Network = "devpod-network"
+	config.Firewall = "firewall-1"
+
+	err := validate(t, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(config *options.Options)
+		expected []string
+	}{
+		{
+			name:   "region typo",
+			change: func(config *options.Options) { config.Region = "LON2" },
+			expected: []string{
+				`CIVO_REGION: region "LON2" is not available, did you mean "LON1"? Available: FRA1, LON1, NYC1`,
+			},
+		},
+		{
+			name:     "region out of capacity",
+			change:   func(config *options.Options) { config.Region = "FRA1" },
+			expected: []string{"CIVO_REGION: region FRA1 is out of capacity"},
+		},
+		{
+			name: "size and image",
+			change: func(config *options.Options) {
+				config.MachineType = "g3.smal"
+				config.DiskImage = "ubuntu-jamy"
+			},
+			expected: []string{
+				`CIVO_INSTANCE_TYPE: instance type "g3.smal" is not available, did you mean "g3.small"? Available: g3.medium, g3.small`,
+				`CIVO_DISK_IMAGE: disk image "ubuntu-jamy" not found in region LON1, did you mean "ubuntu-jammy"?`,
+			},
+		},
+		{
+			name:   "size that isn't selectable",
+			change: func(config *options.Options) { config.MachineType = "g3.k3s.small" },
+			expected: []string{
+				`CIVO_INSTANCE_TYPE: instance type "g3.k3s.small" is not available`,
+			},
+		},
+		{
+			name:   "partial network match",
+			change: func(config *options.Options) { config.Network = "devpod" },
+			expected: []string{
+				`CIVO_NETWORK: network "devpod" not found, set CIVO_NETWORK_CREATE=true to create it`,
+			},
+		},
+		{
+			name:   "partial firewall match",
+			change: func(config *options.Options) { config.Firewall = "devpod" },
+			expected: []string{
+				`CIVO_FIREWALL: firewall "devpod" not found`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := validConfig()
+			test.change(config)
+
+			err := validate(t, config)
+			if err == nil {
+				t.Fatal("expected the options to be invalid")
+			}
+
+			for _, expected := range test.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected the error to contain %q, got:\n%v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCreateNetwork(t *testing.T) {
+	config := validConfig()
+	config.Network = "devpod"
+	config.CreateNetwork = true
+
+	err := validate(t, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
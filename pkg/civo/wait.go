@@ -0,0 +1,218 @@
+package civo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	sshPort         = "22"
+	maxPollInterval = 30 * time.Second
+	dialTimeout     = 5 * time.Second
+)
+
+// InstanceGetter is the part of the civo client the wait functions need, it's
+// implemented by both civogo.Client and civogo.FakeClient.
+type InstanceGetter interface {
+	GetInstance(id string) (*civogo.Instance, error)
+}
+
+// WaitOptions configure how long and how often an instance is polled.
+type WaitOptions struct {
+	Timeout  time.Duration
+	Interval time.Duration
+
+	// MaxInterval caps the exponential backoff between polls
+	MaxInterval time.Duration
+
+	// UsePrivateIP waits for and probes the private instead of the public IP
+	UsePrivateIP bool
+
+	// Probe checks if the instance address accepts connections, defaults to ProbeSSH
+	Probe func(address string) error
+}
+
+// probeSSH is the probe of the provider's waits, tests replace it.
+var probeSSH = ProbeSSH
+
+func waitOptions(civoProvider *CivoProvider) WaitOptions {
+	return WaitOptions{
+		Timeout:      civoProvider.Config.CreateTimeout,
+		Interval:     civoProvider.Config.PollInterval,
+		MaxInterval:  maxPollInterval,
+		UsePrivateIP: !civoProvider.Config.PublicIP,
+		Probe:        probeSSH,
+	}
+}
+
+// WaitForInstance polls the instance until it is ACTIVE with an IP address and
+// sshd accepts connections, returning the ready instance.
+func WaitForInstance(ctx context.Context, client InstanceGetter, instanceID string, options WaitOptions, logs log.Logger) (*civogo.Instance, error) {
+	deadline := time.Now().Add(options.Timeout)
+	interval := options.Interval
+	lastState := ""
+
+	for {
+		instance, state, err := checkInstance(client, instanceID, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if state == "" {
+			logs.Debugf("Instance %s is ready at %s", instanceID, InstanceAddress(instance, options.UsePrivateIP))
+			return instance, nil
+		}
+
+		if state != lastState {
+			logs.Infof("Waiting for instance %s: %s", instanceID, state)
+			lastState = state
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for instance %s: %s", options.Timeout, instanceID, state)
+		}
+
+		err = sleep(ctx, interval)
+		if err != nil {
+			return nil, err
+		}
+		interval = nextInterval(interval, options.MaxInterval)
+	}
+}
+
+// WaitForInstanceDeleted polls the instance until the api doesn't know it
+// anymore.
+func WaitForInstanceDeleted(ctx context.Context, client InstanceGetter, instanceID string, options WaitOptions, logs log.Logger) error {
+	deadline := time.Now().Add(options.Timeout)
+	interval := options.Interval
+
+	for {
+		instance, err := client.GetInstance(instanceID)
+		if IsNotFound(err) {
+			return nil
+		} else if err != nil && !IsRetryable(err) {
+			return errors.Wrapf(err, "get instance %s", instanceID)
+		}
+
+		if instance != nil {
+			logs.Debugf("Waiting for instance %s to be deleted: status is %s", instanceID, instance.Status)
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for instance %s to be deleted", options.Timeout, instanceID)
+		}
+
+		err = sleep(ctx, interval)
+		if err != nil {
+			return err
+		}
+		interval = nextInterval(interval, options.MaxInterval)
+	}
+}
+
+// waitFor polls done until it reports true, for as long as an instance may
+// take to become ready.
+func waitFor(ctx context.Context, civoProvider *CivoProvider, what string, done func() (bool, error)) error {
+	options := waitOptions(civoProvider)
+	deadline := time.Now().Add(options.Timeout)
+	interval := options.Interval
+
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for %s", options.Timeout, what)
+		}
+
+		civoProvider.Log.Debugf("Waiting for %s", what)
+		err = sleep(ctx, interval)
+		if err != nil {
+			return err
+		}
+		interval = nextInterval(interval, options.MaxInterval)
+	}
+}
+
+// checkInstance returns a description of what the instance is still waiting
+// for, or an empty string once it is ready.
+func checkInstance(client InstanceGetter, instanceID string, options WaitOptions) (*civogo.Instance, string, error) {
+	instance, err := client.GetInstance(instanceID)
+	if err != nil && IsRetryable(err) {
+		// keep polling through transient api errors until the timeout
+		return nil, "civo api is unavailable", nil
+	} else if err != nil {
+		return nil, "", errors.Wrapf(err, "get instance %s", instanceID)
+	}
+
+	if IsFailed(instance) {
+		return nil, "", InstanceFailedError(instance)
+	}
+
+	if instance.Status != StatusActive {
+		return instance, "status is " + instance.Status, nil
+	}
+
+	address := InstanceAddress(instance, options.UsePrivateIP)
+	if address == "" {
+		return instance, "ip address is not assigned yet", nil
+	}
+
+	probe := options.Probe
+	if probe == nil {
+		probe = ProbeSSH
+	}
+
+	err = probe(net.JoinHostPort(address, sshPort))
+	if err != nil {
+		return instance, "ssh is not reachable yet", nil
+	}
+
+	return instance, "", nil
+}
+
+// ProbeSSH succeeds once the address answers with an ssh server banner.
+func ProbeSSH(address string) error {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	if err != nil {
+		return err
+	}
+
+	banner, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(banner, "SSH-") {
+		return fmt.Errorf("unexpected ssh banner %q", strings.TrimSpace(banner))
+	}
+
+	return nil
+}
+
+func nextInterval(interval, maxInterval time.Duration) time.Duration {
+	interval *= 2
+	if maxInterval > 0 && interval > maxInterval {
+		return maxInterval
+	}
+
+	return interval
+}
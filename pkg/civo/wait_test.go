@@ -0,0 +1,173 @@
+package civo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/civo/civogo"
+	"github.com/loft-sh/devpod/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// instanceGetterFunc lets a test change the fake instance between polls.
+type instanceGetterFunc func(id string) (*civogo.Instance, error)
+
+func (f instanceGetterFunc) GetInstance(id string) (*civogo.Instance, error) {
+	return f(id)
+}
+
+func newWaitFake(t *testing.T, instance civogo.Instance) *civogo.FakeClient {
+	fake, err := civogo.NewFakeClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.Instances = []civogo.Instance{instance}
+	return fake
+}
+
+func testWaitOptions(probe func(address string) error) WaitOptions {
+	return WaitOptions{
+		Timeout:     time.Second,
+		Interval:    time.Millisecond,
+		MaxInterval: 5 * time.Millisecond,
+		Probe:       probe,
+	}
+}
+
+var testLog = log.NewStreamLogger(io.Discard, io.Discard, logrus.ErrorLevel)
+
+func TestWaitForInstanceReady(t *testing.T) {
+	fake := newWaitFake(t, civogo.Instance{ID: "instance-1", Status: "BUILDING"})
+
+	// the instance becomes active, then gets an ip and then starts sshd
+	polls := 0
+	getter := instanceGetterFunc(func(id string) (*civogo.Instance, error) {
+		polls++
+		switch polls {
+		case 2:
+			fake.Instances[0].Status = StatusActive
+		case 3:
+			fake.Instances[0].PublicIP = "192.0.2.10"
+		}
+
+		return fake.GetInstance(id)
+	})
+
+	probed := []string{}
+	probe := func(address string) error {
+		probed = append(probed, address)
+		if len(probed) == 1 {
+			return errors.New("connection refused")
+		}
+
+		return nil
+	}
+
+	instance, err := WaitForInstance(context.Background(), getter, "instance-1", testWaitOptions(probe), testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if instance.ID != "instance-1" || instance.Status != StatusActive {
+		t.Errorf("expected the active instance, got %s with status %s", instance.ID, instance.Status)
+	}
+	if polls != 4 {
+		t.Errorf("expected 4 polls, got %d", polls)
+	}
+	if len(probed) != 2 || probed[0] != "192.0.2.10:22" {
+		t.Errorf("expected ssh to be probed twice at 192.0.2.10:22, got %v", probed)
+	}
+}
+
+func TestWaitForInstancePrivateIP(t *testing.T) {
+	fake := newWaitFake(t, civogo.Instance{ID: "instance-1", Status: StatusActive, PublicIP: "192.0.2.10", PrivateIP: "10.0.0.5"})
+
+	probed := ""
+	options := testWaitOptions(func(address string) error {
+		probed = address
+		return nil
+	})
+	options.UsePrivateIP = true
+
+	_, err := WaitForInstance(context.Background(), fake, "instance-1", options, testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if probed != "10.0.0.5:22" {
+		t.Errorf("expected the private ip to be probed, got %q", probed)
+	}
+}
+
+func TestWaitForInstanceFailed(t *testing.T) {
+	fake := newWaitFake(t, civogo.Instance{ID: "instance-1", Hostname: "devpod-test", Status: StatusError})
+
+	_, err := WaitForInstance(context.Background(), fake, "instance-1", testWaitOptions(nil), testLog)
+	if err == nil || !strings.Contains(err.Error(), "is in state ERROR") {
+		t.Fatalf("expected the failed instance to be reported, got %v", err)
+	}
+}
+
+func TestWaitForInstanceTimeout(t *testing.T) {
+	fake := newWaitFake(t, civogo.Instance{ID: "instance-1", Status: "BUILDING"})
+
+	options := testWaitOptions(nil)
+	options.Timeout = 20 * time.Millisecond
+
+	_, err := WaitForInstance(context.Background(), fake, "instance-1", options, testLog)
+	if err == nil || !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "status is BUILDING") {
+		t.Fatalf("expected a timeout naming the last state, got %v", err)
+	}
+}
+
+func TestWaitForInstanceCancelled(t *testing.T) {
+	fake := newWaitFake(t, civogo.Instance{ID: "instance-1", Status: "BUILDING"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := WaitForInstance(ctx, fake, "instance-1", testWaitOptions(nil), testLog)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to be cancelled, got %v", err)
+	}
+}
+
+func TestWaitForInstanceNotFound(t *testing.T) {
+	fake := newWaitFake(t, civogo.Instance{ID: "instance-1", Status: StatusActive})
+
+	_, err := WaitForInstance(context.Background(), fake, "instance-2", testWaitOptions(nil), testLog)
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestWaitForInstanceDeleted(t *testing.T) {
+	fake := newWaitFake(t, civogo.Instance{ID: "instance-1", Status: "DELETING"})
+
+	polls := 0
+	getter := instanceGetterFunc(func(id string) (*civogo.Instance, error) {
+		polls++
+		if polls == 3 {
+			_, err := fake.DeleteInstance(id)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return fake.GetInstance(id)
+	})
+
+	err := WaitForInstanceDeleted(context.Background(), getter, "instance-1", testWaitOptions(nil), testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if polls != 3 {
+		t.Errorf("expected 3 polls, got %d", polls)
+	}
+}
@@ -2,22 +2,82 @@ package options
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
 	CIVO_REGION        = "CIVO_REGION"
 	CIVO_INSTANCE_TYPE = "CIVO_INSTANCE_TYPE"
 	CIVO_DISK_IMAGE    = "CIVO_DISK_IMAGE"
+	CIVO_DISK_SIZE     = "CIVO_DISK_SIZE"
+
+	CIVO_VOLUME_SIZE         = "CIVO_VOLUME_SIZE"
+	CIVO_DELETE_VOLUME       = "CIVO_DELETE_VOLUME"
+	CIVO_INIT_SCRIPT         = "CIVO_INIT_SCRIPT"
+	CIVO_NETWORK             = "CIVO_NETWORK"
+	CIVO_NETWORK_CREATE      = "CIVO_NETWORK_CREATE"
+	CIVO_PUBLIC_IP           = "CIVO_PUBLIC_IP"
+	CIVO_RESERVED_IP         = "CIVO_RESERVED_IP"
+	CIVO_RELEASE_RESERVED_IP = "CIVO_RELEASE_RESERVED_IP"
+	CIVO_FIREWALL            = "CIVO_FIREWALL"
+	CIVO_ALLOWED_CIDRS       = "CIVO_ALLOWED_CIDRS"
+	CIVO_TAGS                = "CIVO_TAGS"
+	CIVO_OWNER               = "CIVO_OWNER"
+	CIVO_CREATE_TIMEOUT      = "CIVO_CREATE_TIMEOUT"
+	CIVO_POLL_INTERVAL       = "CIVO_POLL_INTERVAL"
+	CIVO_STOP_MODE           = "CIVO_STOP_MODE"
+	CIVO_POOL                = "CIVO_POOL"
+)
+
+const defaultAgentPath = "/var/lib/toolbox/devpod"
+
+// defaultDiskSizeGB is the disk size if CIVO_DISK_SIZE isn't set, the default
+// of provider.yaml.
+const defaultDiskSizeGB = 40
+
+// MachineIDPrefix is prepended to the DevPod machine ID to name the resources
+// of the machine.
+const MachineIDPrefix = "devpod-"
+
+const (
+	// StopModeShutdown shuts the instance down on stop, civo keeps billing it
+	StopModeShutdown = "shutdown"
+
+	// StopModeHibernate deletes the instance on stop and recreates it on start,
+	// keeping the data volume, reserved IP and firewall
+	StopModeHibernate = "hibernate"
 )
 
 type Options struct {
-	DiskImage     string
-	DiskSizeGB    int
-	MachineFolder string
-	MachineID     string
-	MachineType   string
-	Region        string
+	AgentPath         string
+	AllowedCIDRs      []string
+	CreateNetwork     bool
+	CreateTimeout     time.Duration
+	DeleteVolume      bool
+	DiskImage         string
+	DiskSizeGB        int
+	Firewall          string
+	InitScript        string
+	MachineFolder     string
+	MachineID         string
+	MachineType       string
+	Network           string
+	Owner             string
+	PollInterval      time.Duration
+	Pool              bool
+	PublicIP          bool
+	Region            string
+	ReleaseReservedIP bool
+	ReservedIP        string
+	StopMode          string
+	Tags              []string
+	VolumeSizeGB      int
+	WorkspaceID       string
 }
 
 func ConfigFromEnv() (Options, error) {
@@ -48,6 +108,98 @@ func FromEnv(init, withFolder bool) (*Options, error) {
 		return nil, err
 	}
 
+	retOptions.DiskSizeGB = defaultDiskSizeGB
+	if diskSizeGB := os.Getenv(CIVO_DISK_SIZE); diskSizeGB != "" {
+		retOptions.DiskSizeGB, err = parseSize(CIVO_DISK_SIZE, diskSizeGB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	retOptions.InitScript, err = initScriptFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	volumeSizeGB := os.Getenv(CIVO_VOLUME_SIZE)
+	if volumeSizeGB != "" && volumeSizeGB != "0" {
+		retOptions.VolumeSizeGB, err = parseSize(CIVO_VOLUME_SIZE, volumeSizeGB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	retOptions.DeleteVolume, err = boolFromEnv(CIVO_DELETE_VOLUME, false)
+	if err != nil {
+		return nil, err
+	}
+
+	retOptions.Network = os.Getenv(CIVO_NETWORK)
+
+	retOptions.CreateNetwork, err = boolFromEnv(CIVO_NETWORK_CREATE, false)
+	if err != nil {
+		return nil, err
+	}
+
+	retOptions.PublicIP, err = boolFromEnv(CIVO_PUBLIC_IP, true)
+	if err != nil {
+		return nil, err
+	}
+
+	retOptions.ReservedIP = os.Getenv(CIVO_RESERVED_IP)
+	if retOptions.ReservedIP == "false" {
+		retOptions.ReservedIP = ""
+	} else if retOptions.ReservedIP != "" && !retOptions.PublicIP {
+		return nil, fmt.Errorf("option %s requires %s to be enabled", CIVO_RESERVED_IP, CIVO_PUBLIC_IP)
+	}
+
+	retOptions.ReleaseReservedIP, err = boolFromEnv(CIVO_RELEASE_RESERVED_IP, false)
+	if err != nil {
+		return nil, err
+	}
+
+	retOptions.StopMode = strings.ToLower(os.Getenv(CIVO_STOP_MODE))
+	if retOptions.StopMode == "" {
+		retOptions.StopMode = StopModeShutdown
+	} else if retOptions.StopMode != StopModeShutdown && retOptions.StopMode != StopModeHibernate {
+		return nil, fmt.Errorf("invalid value %q for option %s, expected %s or %s", retOptions.StopMode, CIVO_STOP_MODE, StopModeShutdown, StopModeHibernate)
+	} else if retOptions.StopMode == StopModeHibernate && retOptions.VolumeSizeGB == 0 {
+		return nil, fmt.Errorf("option %s=%s requires %s, which holds the workspace while the instance is deleted", CIVO_STOP_MODE, StopModeHibernate, CIVO_VOLUME_SIZE)
+	}
+
+	retOptions.Pool, err = boolFromEnv(CIVO_POOL, false)
+	if err != nil {
+		return nil, err
+	}
+
+	retOptions.Firewall = os.Getenv(CIVO_FIREWALL)
+
+	retOptions.AllowedCIDRs, err = allowedCIDRsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	retOptions.Tags = strings.FieldsFunc(os.Getenv(CIVO_TAGS), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	retOptions.Owner = OwnerFromEnv()
+
+	retOptions.AgentPath = os.Getenv("AGENT_PATH")
+	if retOptions.AgentPath == "" {
+		retOptions.AgentPath = defaultAgentPath
+	}
+
+	retOptions.CreateTimeout, err = durationFromEnv(CIVO_CREATE_TIMEOUT, 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	retOptions.PollInterval, err = durationFromEnv(CIVO_POLL_INTERVAL, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
 	// Return eraly if we're just doing init
 	if init {
 		return retOptions, nil
@@ -58,7 +210,8 @@ func FromEnv(init, withFolder bool) (*Options, error) {
 		return nil, err
 	}
 	// prefix with devpod-
-	retOptions.MachineID = "devpod-" + retOptions.MachineID
+	retOptions.MachineID = MachineIDPrefix + retOptions.MachineID
+	retOptions.WorkspaceID = os.Getenv("WORKSPACE_ID")
 
 	if withFolder {
 		retOptions.MachineFolder, err = fromEnvOrError("MACHINE_FOLDER")
@@ -82,3 +235,120 @@ func fromEnvOrError(name string) (string, error) {
 
 	return val, nil
 }
+
+func parseSize(name, val string) (int, error) {
+	size, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q for option %s, expected a number of gigabytes", val, name)
+	}
+
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid value %d for option %s, must be greater than 0", size, name)
+	}
+
+	return size, nil
+}
+
+func boolFromEnv(name string, defaultValue bool) (bool, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for option %s, expected true or false", val, name)
+	}
+
+	return b, nil
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q for option %s, expected a duration like 10m", val, name)
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("invalid value %q for option %s, must be greater than 0", val, name)
+	}
+
+	return duration, nil
+}
+
+// initScriptFromEnv returns CIVO_INIT_SCRIPT, reading the file if the value
+// is a path to one. A value that looks like a path but doesn't point to a file
+// is an error rather than being run as a script.
+func initScriptFromEnv() (string, error) {
+	val := os.Getenv(CIVO_INIT_SCRIPT)
+	if val == "" || strings.Contains(val, "\n") {
+		return val, nil
+	}
+
+	looksLikePath := !strings.ContainsAny(val, " \t") && (strings.Contains(val, "/") || strings.HasSuffix(val, ".sh"))
+	info, err := os.Stat(val)
+	if err == nil && info.IsDir() {
+		err = fmt.Errorf("%s is a directory", val)
+	}
+	if err != nil {
+		if looksLikePath {
+			return "", fmt.Errorf("invalid value %q for option %s, the script file can't be read: %v", val, CIVO_INIT_SCRIPT, err)
+		}
+
+		return val, nil
+	}
+
+	content, err := os.ReadFile(val)
+	if err != nil {
+		return "", fmt.Errorf("read init script %s: %w", val, err)
+	}
+
+	return string(content), nil
+}
+
+// allowedCIDRsFromEnv parses the comma separated CIVO_ALLOWED_CIDRS, where
+// "egress" stands for the public IP of the machine running the provider.
+func allowedCIDRsFromEnv() ([]string, error) {
+	cidrs := []string{}
+	for _, cidr := range strings.Split(os.Getenv(CIVO_ALLOWED_CIDRS), ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if cidr != "egress" {
+			_, _, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q in option %s", cidr, CIVO_ALLOWED_CIDRS)
+			}
+		}
+
+		cidrs = append(cidrs, cidr)
+	}
+
+	return cidrs, nil
+}
+
+// OwnerFromEnv returns CIVO_OWNER, defaulting to the local user name.
+func OwnerFromEnv() string {
+	owner := os.Getenv(CIVO_OWNER)
+	if owner == "" {
+		return currentUser()
+	}
+
+	return owner
+}
+
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return u.Username
+}
@@ -0,0 +1,4 @@
+package version
+
+// Version is the provider version, set at build time via ldflags.
+var Version = "dev"